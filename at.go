@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// positionPattern matches a file:line position, editors often add the column as well, IE foo_test.go:42:7.
+var positionPattern = regexp.MustCompile(`^(.+?):(\d+)(?::\d+)?$`)
+
+// runAt runs, or debugs with -d, the innermost test, t.Run subtest or table row containing pos.
+func runAt(pos string) error {
+	file, line, err := parsePosition(pos)
+	if err != nil {
+		return err
+	}
+
+	file, err = filepath.Abs(file)
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %w", file, err)
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		return err
+	}
+
+	available, err := getTestsFromDir(filepath.Dir(file), kindTest)
+	if err != nil {
+		return fmt.Errorf("error getting tests: %w", err)
+	}
+
+	tests := testsAt(available, file, line)
+	if len(tests) == 0 {
+		return fmt.Errorf("no test found at %s", pos)
+	}
+
+	return runTests(tests)
+}
+
+// parsePosition splits a file:line position.
+func parsePosition(pos string) (string, int, error) {
+	match := positionPattern.FindStringSubmatch(pos)
+	if match == nil {
+		return "", 0, fmt.Errorf("invalid position %q, expected file:line IE foo_test.go:42", pos)
+	}
+
+	line, err := strconv.Atoi(match[2])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid line in %q: %w", pos, err)
+	}
+
+	return match[1], line, nil
+}
+
+// testsAt returns the innermost tests containing line of file. Only one test usually does, but the rows of a table
+// share the closure passed to t.Run so a line in it belongs to every row. Those are combined into a single test with
+// the segments of the names that differ replaced by *, IE TestX/* for every row of TestX's table.
+func testsAt(tests []Test, file string, line int) []Test {
+	var found []Test
+	best := -1
+	for _, t := range tests {
+		span := lineSpan(t, file, line)
+		switch {
+		case span < 0:
+			continue
+		case best < 0 || span < best:
+			found, best = []Test{t}, span
+		case span == best:
+			found = append(found, t)
+		}
+	}
+
+	if len(found) < 2 {
+		return found
+	}
+
+	return mergeTests(found)
+}
+
+// lineSpan returns how many lines the innermost part of t containing line of file spans, either where the test is
+// defined or the body of its t.Run closure. It's -1 when t doesn't contain the line.
+func lineSpan(t Test, file string, line int) int {
+	span := -1
+	if samePath(t.FilePath, file) && t.LineNumber <= line && line <= t.EndLineNumber {
+		span = t.EndLineNumber - t.LineNumber
+	}
+
+	if t.BodyLineNumber != 0 && samePath(t.File, file) && t.BodyLineNumber <= line && line <= t.BodyEndLineNumber {
+		if body := t.BodyEndLineNumber - t.BodyLineNumber; span < 0 || body < span {
+			span = body
+		}
+	}
+
+	return span
+}
+
+// samePath reports if a and b are the same file, relative paths are resolved against the working directory.
+func samePath(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// mergeTests combines tests whose names have the same number of segments into a wildcard test, the segments that
+// differ become *. Tests that can't be combined are returned as is.
+func mergeTests(tests []Test) []Test {
+	segments := strings.Split(tests[0].Name, "/")
+	for _, t := range tests[1:] {
+		other := strings.Split(t.Name, "/")
+		if len(other) != len(segments) {
+			return tests
+		}
+
+		for i := range segments {
+			if segments[i] != other[i] {
+				segments[i] = "*"
+			}
+		}
+	}
+
+	merged := tests[0]
+	merged.Name = strings.Join(segments, "/")
+	merged.IsWildcard = true
+	// the debugger stops for every row rather than only the first row's name
+	merged.TestingParam = ""
+
+	return []Test{merged}
+}
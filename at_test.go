@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parsePosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		pos     string
+		file    string
+		line    int
+		wantErr bool
+	}{
+		{name: "file and line", pos: "foo_test.go:42", file: "foo_test.go", line: 42},
+		{name: "with column", pos: "pkg/foo_test.go:42:7", file: "pkg/foo_test.go", line: 42},
+		{name: "no line", pos: "foo_test.go", wantErr: true},
+		{name: "bad line", pos: "foo_test.go:x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, line, err := parsePosition(tt.pos)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.file, file)
+			assert.Equal(t, tt.line, line)
+		})
+	}
+}
+
+func Test_testsAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		line  int
+		names []string
+		// wildcard is set when the tests found are combined into one
+		wildcard bool
+	}{
+		{name: "test function", file: "testdata/t_run_for_loop.go", line: 6, names: []string{"Test_ForLoop"}},
+		{name: "table row", file: "testdata/t_run_for_loop.go", line: 10, names: []string{"Test_ForLoop/test2"}},
+		{name: "closure shared by rows", file: "testdata/t_run_for_loop.go", line: 16, names: []string{"Test_ForLoop/*"}, wildcard: true},
+		{name: "nested t.Run", file: "testdata/nested_t_run_string.go", line: 10, names: []string{"Test_Nested/L1/L2"}},
+		{name: "outer t.Run", file: "testdata/nested_t_run_string.go", line: 11, names: []string{"Test_Nested/L1"}},
+		{name: "table in another file", file: "testdata/package_table_cases.go", line: 15, names: []string{"Test_OtherFileTable/other2"}},
+		{name: "outside of any test", file: "testdata/t_run_for_loop.go", line: 3},
+	}
+
+	available := append(findTests("testdata/t_run_for_loop.go"), findTests("testdata/nested_t_run_string.go")...)
+	available = append(available, findTests("testdata/package_table.go")...)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			found := testsAt(available, tt.file, tt.line)
+			for _, test := range found {
+				names = append(names, test.Name)
+				assert.Equal(t, tt.wildcard, test.IsWildcard)
+			}
+
+			assert.Equal(t, tt.names, names)
+		})
+	}
+}
+
+func Test_mergeTests(t *testing.T) {
+	tests := []struct {
+		name  string
+		tests []string
+		want  []string
+	}{
+		{name: "rows", tests: []string{"TestX/a", "TestX/b"}, want: []string{"TestX/*"}},
+		{name: "nested under rows", tests: []string{"TestX/a/check", "TestX/b/check"}, want: []string{"TestX/*/check"}},
+		{name: "different depths", tests: []string{"TestX/a", "TestX/b/check"}, want: []string{"TestX/a", "TestX/b/check"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in []Test
+			for _, name := range tt.tests {
+				in = append(in, Test{Name: name, TestingParam: "t"})
+			}
+
+			var names []string
+			for _, test := range mergeTests(in) {
+				names = append(names, test.Name)
+			}
+
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
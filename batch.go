@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// testArgs returns the go test arguments that run tests from the package at path in the module at modRoot. The
+// tests are combined into a single -run pattern, go test matches each alternative on its own so subtests of
+// different tests can be mixed. flags are added after the test selection, the extra arguments go last so they can
+// override any of the others.
+func testArgs(path, modRoot string, tests []Test, flags ...string) []string {
+	args := []string{"test", quietMode(), path}
+	args = append(args, buildFlags()...)
+
+	var patterns, methods []string
+	for _, t := range tests {
+		// running the whole package includes everything else
+		if t.Name == "" {
+			args = append(args, flags...)
+			return append(args, extraArgs(modRoot)...)
+		}
+
+		patterns = append(patterns, testRunPattern(t))
+		if t.SuiteMethod != "" {
+			methods = append(methods, runPattern(t.SuiteMethod))
+		}
+	}
+
+	args = append(args, "-run", strings.Join(patterns, "|"))
+
+	// testify runs every suite method unless told otherwise, which would also run their setup and teardown. Whole
+	// suites selected alongside methods have to run every method so -testify.m is only used for methods alone.
+	if len(methods) == len(tests) {
+		slices.Sort(methods)
+		args = append(args, "-testify.m", strings.Join(slices.Compact(methods), "|"))
+	}
+
+	args = append(args, flags...)
+	return append(args, extraArgs(modRoot)...)
+}
+
+// batchCommands groups tests by package and returns one go test command for each package.
+func batchCommands(tests []Test) []exec.Cmd {
+	p, err := exec.LookPath("go")
+	if err != nil {
+		panic(err)
+	}
+
+	type pkgTests struct {
+		path    string
+		modRoot string
+		tests   []Test
+	}
+
+	var pkgs []*pkgTests
+	for _, t := range tests {
+		path, modRoot := testToPathAndRoot(t)
+
+		i := slices.IndexFunc(pkgs, func(pkg *pkgTests) bool {
+			return pkg.path == path && pkg.modRoot == modRoot
+		})
+		if i < 0 {
+			pkgs = append(pkgs, &pkgTests{path: path, modRoot: modRoot})
+			i = len(pkgs) - 1
+		}
+
+		pkgs[i].tests = append(pkgs[i].tests, t)
+	}
+
+	cmds := make([]exec.Cmd, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		cmds = append(cmds, exec.Cmd{
+			Path: p,
+			Args: append([]string{"go"}, append(testArgs(pkg.path, pkg.modRoot, pkg.tests), "-json")...),
+			Dir:  pkg.modRoot,
+		})
+	}
+
+	return cmds
+}
+
+// executeBatch runs tests from any number of packages, one go test command per package, with a single summary at
+// the end. It returns the commands that were run and their outcome.
+func executeBatch(tests []Test) ([]exec.Cmd, runOutcome, error) {
+	if *debug || *withCoverage || *withCPUProfile || *withMemoryProfile {
+		return nil, runOutcome{}, errors.New("-d, -cover, -cpu and -mem only support running a single test")
+	}
+
+	cmds := batchCommands(tests)
+	outcome := runBatch(cmds)
+
+	return cmds, outcome, nil
+}
+
+// runBatch runs each of the commands in turn, the outcome only passes when they all passed.
+func runBatch(cmds []exec.Cmd) runOutcome {
+	events := newTestEventWriter(os.Stdout)
+
+	// results are kept per module since the last run of each one is what -rf reruns
+	results := map[string]map[string]map[string]string{}
+
+	pass := true
+	for i := range cmds {
+		cmd := exec.Cmd{
+			Path:   cmds[i].Path,
+			Env:    os.Environ(),
+			Args:   cmds[i].Args,
+			Dir:    cmds[i].Dir,
+			Stdout: events,
+			Stderr: os.Stderr,
+		}
+
+		fmt.Println("Running", cmd.Args, "@", cmd.Dir)
+
+		err := runCommand(&cmd, events)
+		var exit *exec.ExitError
+		switch {
+		case err == nil:
+		case errors.As(err, &exit):
+			pass = false
+		default:
+			panic(err)
+		}
+
+		events.Flush()
+
+		if results[cmd.Dir] == nil {
+			results[cmd.Dir] = map[string]map[string]string{}
+		}
+
+		maps.Copy(results[cmd.Dir], events.takeResults())
+	}
+
+	events.PrintSummary()
+	writeJUnitReport(events)
+	reportRaces(events)
+	hangDump := reportHang(events)
+	storeLearnedTests(events.seen)
+	for dir, dirResults := range results {
+		storeRunResults(dir, dirResults)
+	}
+
+	return runOutcome{Pass: pass, Races: events.races, HangDump: hangDump}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_testArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		tests    []Test
+		expected []string
+	}{
+		{
+			name:     "whole package",
+			tests:    []Test{{}},
+			expected: []string{"test", "-v", "./pkg"},
+		},
+		{
+			name:     "subtests of different tests",
+			tests:    []Test{{Name: "TestA/one"}, {Name: "TestB"}},
+			expected: []string{"test", "-v", "./pkg", "-run", "^TestA$/^one$|^TestB$"},
+		},
+		{
+			name:     "suite methods",
+			tests:    []Test{{Name: "TestSuite/TestB", SuiteMethod: "TestB"}, {Name: "TestSuite/TestA", SuiteMethod: "TestA"}},
+			expected: []string{"test", "-v", "./pkg", "-run", "^TestSuite$/^TestB$|^TestSuite$/^TestA$", "-testify.m", "^TestA$|^TestB$"},
+		},
+		{
+			name:     "whole suite alongside a method",
+			tests:    []Test{{Name: "TestSuite"}, {Name: "TestOther/TestA", SuiteMethod: "TestA"}},
+			expected: []string{"test", "-v", "./pkg", "-run", "^TestSuite$|^TestOther$/^TestA$"},
+		},
+	}
+
+	quiet = boolPtr(false)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, testArgs("./pkg", "/mod", tt.tests))
+		})
+	}
+
+	t.Run("extra args go last", func(t *testing.T) {
+		t.Cleanup(func() { passThroughArgs = nil })
+		passThroughArgs = []string{"-count=1", "-run", "TestC"}
+
+		assert.Equal(t, []string{"test", "-v", "./pkg", "-count=1", "-run", "TestC"}, testArgs("./pkg", "/mod", []Test{{}}))
+		assert.Equal(t, []string{"test", "-v", "./pkg", "-run", "^TestA$", "-count=1", "-run", "TestC"},
+			testArgs("./pkg", "/mod", []Test{{Name: "TestA"}}))
+		assert.Equal(t, []string{"test", "-v", "./pkg", "-run", "^TestA$", "-shuffle=on", "-count=1", "-run", "TestC"},
+			testArgs("./pkg", "/mod", []Test{{Name: "TestA"}}, "-shuffle=on"))
+	})
+}
+
+func Test_batchCommands(t *testing.T) {
+	quiet = boolPtr(false)
+
+	testdata, err := filepath.Abs("testdata/t_run_for_loop.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flamegraph, err := filepath.Abs("pkg/flamegraph/flamegraph_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := batchCommands([]Test{
+		{File: testdata, Name: "Test_ForLoop/test1"},
+		{File: flamegraph, Name: "Test_profileToRaw"},
+		{File: testdata, Name: "Test_Nested"},
+	})
+
+	var args [][]string
+	for _, cmd := range cmds {
+		args = append(args, cmd.Args)
+	}
+
+	assert.Equal(t, [][]string{
+		{"go", "test", "-v", "./testdata", "-run", "^Test_ForLoop$/^test1$|^Test_Nested$", "-json"},
+		{"go", "test", "-v", "./pkg/flamegraph", "-run", "^Test_profileToRaw$", "-json"},
+	}, args)
+}
@@ -21,10 +21,14 @@ func runBenchmark(t Test) {
 
 	// create base args with verbose and a run that filters tests so we only run benchmarks
 	args := []string{"test", "-v", path, "-run", "XXX"}
+	args = append(args, buildFlags()...)
+
 	if t.Name != "" {
-		args = append(args, "-bench", t.Name)
+		args = append(args, "-bench", runPattern(t.Name))
 	}
 
+	args = append(args, extraArgs(modRoot)...)
+
 	var cpuProfile string
 	if *withCPUProfile {
 		tempFile, err := os.CreateTemp("", "go-test_"+t.Name)
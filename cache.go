@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// discoveryBucket stores discovered tests per package directory in the history file.
+const discoveryBucket = "discovery"
+
+// discoveryCacheVersion is part of every cache key, bump it when discovery changes so old results aren't reused.
+const discoveryCacheVersion = 4
+
+// fileStamp identifies a version of a file without having to read it.
+type fileStamp struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// discoveryEntry is the cached discovery result for a single package directory. The tests are only valid while
+// every .go file in the directory still matches its stamp, since tables and helpers can live in non test files.
+type discoveryEntry struct {
+	Files map[string]fileStamp
+	Tests []Test
+}
+
+// discoveryKey includes the build context since GOOS, GOARCH and build tags change which files are compiled, and
+// the helper depth since it changes which subtests are found.
+func discoveryKey(kind testKind, dir string) []byte {
+	return []byte(fmt.Sprintf("v%d:%d:%s:%s:%d:%s", discoveryCacheVersion, kind, buildContext(),
+		strings.Join(buildFlags(), " "), globalConfig.HelperDepth, dir))
+}
+
+// buildContext describes the platform tests are built for.
+func buildContext() string {
+	goos, goarch := os.Getenv("GOOS"), os.Getenv("GOARCH")
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+
+	return goos + "/" + goarch + " " + os.Getenv("GOFLAGS")
+}
+
+// packageDir is a directory containing .go files and the module that owns it.
+type packageDir struct {
+	ModRoot string
+	Files   map[string]fileStamp
+}
+
+// scanPackageDirs returns every directory under root containing .go files along with the stamps of those files.
+// Directories go list ignores for ./... are skipped so the result lines up with what packages.Load returns. Nested
+// modules are included, each directory records the module root that owns it.
+func scanPackageDirs(root string) (map[string]packageDir, error) {
+	rootModule := lookupModuleRoot(root)
+
+	modRoots := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		modRoot := rootModule
+		if path != root {
+			if skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+
+			modRoot = modRoots[filepath.Dir(path)]
+		}
+
+		// a go.mod starts a new module for this directory and everything below it
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+			modRoot = path
+		}
+
+		modRoots[path] = modRoot
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// stat the files of each directory concurrently, this is most of the time spent on large repos
+	var mu sync.Mutex
+	dirs := map[string]packageDir{}
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for dir := range work {
+				files := statGoFiles(dir)
+				if len(files) == 0 {
+					continue
+				}
+
+				mu.Lock()
+				dirs[dir] = packageDir{ModRoot: modRoots[dir], Files: files}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for dir, modRoot := range modRoots {
+		// directories outside of any module can't be loaded
+		if modRoot != "" {
+			work <- dir
+		}
+	}
+
+	close(work)
+	wg.Wait()
+
+	return dirs, nil
+}
+
+// skipDir reports if go list ignores the directory when matching ./...
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor"
+}
+
+// statGoFiles returns the stamps of the .go files directly within dir.
+func statGoFiles(dir string) map[string]fileStamp {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	files := map[string]fileStamp{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		files[entry.Name()] = fileStamp{Size: info.Size(), ModTime: info.ModTime()}
+	}
+
+	return files
+}
+
+// lookupDiscoveryCache returns the cached tests of every directory whose files haven't changed, along with the
+// directories that need to be loaded again.
+func lookupDiscoveryCache(kind testKind, dirs map[string]packageDir) (map[string][]Test, []string) {
+	found := map[string][]Test{}
+	var stale []string
+
+	db := getHistoryFile(historyFile)
+	defer db.Close()
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(discoveryBucket))
+
+		for dir, pkgDir := range dirs {
+			var entry discoveryEntry
+			if b != nil {
+				if data := b.Get(discoveryKey(kind, dir)); data != nil {
+					if err := json.Unmarshal(data, &entry); err != nil && *verbose {
+						fmt.Println("Ignoring invalid discovery cache for", dir, err)
+					}
+				}
+			}
+
+			if !sameStamps(entry.Files, pkgDir.Files) {
+				stale = append(stale, dir)
+				continue
+			}
+
+			found[dir] = entry.Tests
+		}
+
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	slices.Sort(stale)
+
+	if *verbose {
+		fmt.Printf("Discovery cache: %d packages cached, %d to load\n", len(found), len(stale))
+	}
+
+	return found, stale
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+
+	for name, stamp := range a {
+		other, ok := b[name]
+		if !ok || other.Size != stamp.Size || !other.ModTime.Equal(stamp.ModTime) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// storeDiscoveryCache saves the tests found for each of the loaded directories.
+func storeDiscoveryCache(kind testKind, loaded []string, dirs map[string]packageDir, discovered map[string][]Test) {
+	db := getHistoryFile(historyFile)
+	defer db.Close()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(discoveryBucket))
+		if err != nil {
+			return err
+		}
+
+		for _, dir := range loaded {
+			data, err := json.Marshal(discoveryEntry{Files: dirs[dir].Files, Tests: discovered[dir]})
+			if err != nil {
+				return err
+			}
+
+			err = b.Put(discoveryKey(kind, dir), data)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// discoverPackages finds the tests in each package using a pool of workers and groups them by directory.
+func discoverPackages(pkgs []*testPackage, kind testKind) map[string][]Test {
+	results := make([][]Test, len(pkgs))
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range work {
+				results[i] = pkgs[i].discover(kind)
+			}
+		}()
+	}
+
+	for i := range pkgs {
+		work <- i
+	}
+
+	close(work)
+	wg.Wait()
+
+	// a directory can hold both the package and its _test package, keep their tests ordered by file
+	byDir := map[string][]Test{}
+	for _, tests := range results {
+		for _, test := range tests {
+			dir := filepath.Dir(test.File)
+			byDir[dir] = append(byDir[dir], test)
+		}
+	}
+
+	for _, tests := range byDir {
+		slices.SortStableFunc(tests, func(a, b Test) int {
+			return strings.Compare(a.File, b.File)
+		})
+	}
+
+	return byDir
+}
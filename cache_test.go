@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getTestsFromDirCache(t *testing.T) {
+	isolateHistory(t)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/cache\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(dir, "a_test.go"), "package cache\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {}\n")
+
+	tests, err := getTestsFromDir(dir, kindTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"TestA"}, testNames(tests))
+
+	dirs, err := scanPackageDirs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing changed so everything should come from the cache
+	_, stale := lookupDiscoveryCache(kindTest, dirs)
+	assert.Empty(t, stale)
+
+	// changing a file invalidates the package
+	writeFile(t, filepath.Join(dir, "a_test.go"), "package cache\n\nimport \"testing\"\n\nfunc TestB(t *testing.T) {}\n")
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "a_test.go"), future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	tests, err = getTestsFromDir(dir, kindTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"TestB"}, testNames(tests))
+}
+
+// isolateHistory keeps the history file out of the users home, while still using the real build cache so loading
+// packages stays fast.
+func isolateHistory(t *testing.T) {
+	t.Helper()
+
+	goCache, err := exec.Command("go", "env", "GOCACHE").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GOCACHE", strings.TrimSpace(string(goCache)))
+	t.Setenv("HOME", t.TempDir())
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testNames(tests []Test) []string {
+	var names []string
+	for _, test := range tests {
+		names = append(names, test.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// listedPackage is the part of the go list -json output needed to find the packages affected by a change.
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	Standard   bool
+	Module     *struct {
+		Main bool
+	}
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// runChanged runs the tests of every package in the module of dir affected by the changes git reports, either
+// because a package changed or because it imports one that did.
+func runChanged(dir string) error {
+	modRoot := lookupModuleRoot(dir)
+	if modRoot == "" {
+		return errors.New("no module found")
+	}
+
+	changed, err := changedFiles(modRoot, *changedStaged, *changedBase)
+	if err != nil {
+		return fmt.Errorf("error getting changed files: %w", err)
+	}
+
+	pkgs, err := listPackages(modRoot)
+	if err != nil {
+		return fmt.Errorf("error listing packages: %w", err)
+	}
+
+	affected := affectedPackages(pkgs, changed, modRoot)
+	if len(affected) == 0 {
+		fmt.Println("No tests affected by the changes")
+		return nil
+	}
+
+	p, err := exec.LookPath("go")
+	if err != nil {
+		panic(err)
+	}
+
+	args := []string{"go", "test", quietMode()}
+	for _, pkg := range affected {
+		fmt.Println("Affected:", pkg.ImportPath)
+		args = append(args, "./"+filepath.ToSlash(packageFromPathAndMod(pkg.Dir, modRoot)))
+	}
+
+	args = append(args, buildFlags()...)
+	args = append(args, extraArgs(modRoot)...)
+	args = append(args, "-json")
+
+	cmds := []exec.Cmd{{Path: p, Args: args, Dir: modRoot}}
+	logBatchHistory(cmds, runBatch(cmds))
+
+	return nil
+}
+
+// changedFiles returns the absolute paths of the files git reports as changed in the repository containing dir.
+// By default that's everything that differs from HEAD including untracked files, staged only looks at the index and
+// base compares against the point the current branch forked from base, IE origin/main, including uncommitted work.
+func changedFiles(dir string, staged bool, base string) ([]string, error) {
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	top = strings.TrimSpace(top)
+
+	// new files count as changes unless only staged changes are wanted
+	var outputs []string
+	if !staged {
+		untracked, err := git(dir, "ls-files", "--others", "--exclude-standard")
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, untracked)
+	}
+
+	switch {
+	case staged:
+		out, err := git(dir, "diff", "--name-only", "--cached")
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, out)
+
+	case base != "":
+		// committed changes since the branch point as well as the working tree
+		mergeBase, err := git(dir, "merge-base", base, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := git(dir, "diff", "--name-only", strings.TrimSpace(mergeBase))
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, out)
+
+	default:
+		out, err := git(dir, "diff", "--name-only", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, out)
+	}
+
+	var files []string
+	for _, out := range outputs {
+		for _, name := range strings.Split(out, "\n") {
+			if name != "" {
+				files = append(files, filepath.Join(top, filepath.FromSlash(name)))
+			}
+		}
+	}
+
+	slices.Sort(files)
+
+	return slices.Compact(files), nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return string(out), nil
+}
+
+// listPackages returns every package of the module at modRoot along with its dependencies.
+func listPackages(modRoot string) ([]listedPackage, error) {
+	args := append([]string{"list", "-deps", "-json"}, buildFlags()...)
+	cmd := exec.Command("go", append(args, "./...")...)
+	cmd.Dir = modRoot
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg listedPackage
+		err := dec.Decode(&pkg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error decoding go list output: %w", err)
+		}
+
+		if !pkg.Standard {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	return pkgs, nil
+}
+
+// affectedPackages returns the packages with tests that are affected by the changed files. A package is affected
+// when one of its files changed, a file in its testdata changed, or it or its tests import an affected package.
+// A change to the go.mod or go.sum of the module affects every package.
+func affectedPackages(pkgs []listedPackage, changed []string, modRoot string) []listedPackage {
+	byDir := map[string]string{}
+	for _, pkg := range pkgs {
+		byDir[pkg.Dir] = pkg.ImportPath
+	}
+
+	dirty := map[string]bool{}
+	for _, file := range changed {
+		name := filepath.Base(file)
+		if filepath.Dir(file) == modRoot && (name == "go.mod" || name == "go.sum") {
+			for _, pkg := range pkgs {
+				dirty[pkg.ImportPath] = true
+			}
+
+			break
+		}
+
+		dir, isFixture := testdataOwner(filepath.Dir(file))
+		if !isFixture && filepath.Ext(file) != ".go" {
+			continue
+		}
+
+		if importPath, ok := byDir[dir]; ok {
+			dirty[importPath] = true
+		}
+	}
+
+	// packages that import a dirty package are dirty too
+	importers := map[string][]string{}
+	for _, pkg := range pkgs {
+		for _, imp := range pkg.Imports {
+			importers[imp] = append(importers[imp], pkg.ImportPath)
+		}
+	}
+
+	queue := make([]string, 0, len(dirty))
+	for importPath := range dirty {
+		queue = append(queue, importPath)
+	}
+
+	for len(queue) > 0 {
+		importPath := queue[0]
+		queue = queue[1:]
+
+		for _, importer := range importers[importPath] {
+			if !dirty[importer] {
+				dirty[importer] = true
+				queue = append(queue, importer)
+			}
+		}
+	}
+
+	var affected []listedPackage
+	for _, pkg := range pkgs {
+		if len(pkg.TestGoFiles) == 0 && len(pkg.XTestGoFiles) == 0 {
+			continue
+		}
+
+		// only packages in the module have their tests run
+		if pkg.Module == nil || !pkg.Module.Main {
+			continue
+		}
+
+		isAffected := dirty[pkg.ImportPath]
+		for _, imp := range slices.Concat(pkg.TestImports, pkg.XTestImports) {
+			isAffected = isAffected || dirty[imp]
+		}
+
+		if isAffected {
+			affected = append(affected, pkg)
+		}
+	}
+
+	slices.SortFunc(affected, func(a, b listedPackage) int {
+		return strings.Compare(a.ImportPath, b.ImportPath)
+	})
+
+	return affected
+}
+
+// testdataOwner returns the package directory owning dir when dir is within a testdata directory, fixtures are only
+// used by the tests of that package.
+func testdataOwner(dir string) (string, bool) {
+	for d := dir; d != filepath.Dir(d); d = filepath.Dir(d) {
+		if filepath.Base(d) == "testdata" {
+			return filepath.Dir(d), true
+		}
+	}
+
+	return dir, false
+}
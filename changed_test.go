@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_affectedPackages(t *testing.T) {
+	mainModule := &struct{ Main bool }{Main: true}
+	pkgs := []listedPackage{
+		{ImportPath: "example.com/dep", Dir: "/mod/vendored/dep", Imports: []string{}},
+		{ImportPath: "example.com/m/a", Dir: "/mod/a", Module: mainModule, TestGoFiles: []string{"a_test.go"}},
+		{ImportPath: "example.com/m/b", Dir: "/mod/b", Module: mainModule, Imports: []string{"example.com/m/a"}},
+		{ImportPath: "example.com/m/c", Dir: "/mod/c", Module: mainModule, Imports: []string{"example.com/m/b"}, TestGoFiles: []string{"c_test.go"}},
+		{ImportPath: "example.com/m/d", Dir: "/mod/d", Module: mainModule, XTestImports: []string{"example.com/m/b"}, XTestGoFiles: []string{"d_test.go"}},
+		{ImportPath: "example.com/m/e", Dir: "/mod/e", Module: mainModule, TestGoFiles: []string{"e_test.go"}},
+	}
+
+	tests := []struct {
+		name     string
+		changed  []string
+		expected []string
+	}{
+		{
+			name:     "importers and their tests",
+			changed:  []string{"/mod/a/a.go"},
+			expected: []string{"example.com/m/a", "example.com/m/c", "example.com/m/d"},
+		},
+		{
+			name:     "testdata belongs to its package",
+			changed:  []string{"/mod/e/testdata/golden/out.txt"},
+			expected: []string{"example.com/m/e"},
+		},
+		{
+			name:    "non go files are ignored",
+			changed: []string{"/mod/a/README.md", "/mod/docs/index.go"},
+		},
+		{
+			name:     "go.mod affects everything",
+			changed:  []string{"/mod/go.mod"},
+			expected: []string{"example.com/m/a", "example.com/m/c", "example.com/m/d", "example.com/m/e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var affected []string
+			for _, pkg := range affectedPackages(pkgs, tt.changed, "/mod") {
+				affected = append(affected, pkg.ImportPath)
+			}
+
+			assert.Equal(t, tt.expected, affected)
+		})
+	}
+}
+
+func Test_changedFiles(t *testing.T) {
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	writeFile(t, filepath.Join(dir, "a.go"), "package a\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package a\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "initial")
+
+	run("checkout", "-q", "-b", "feature")
+	writeFile(t, filepath.Join(dir, "a.go"), "package a\n\nvar A = 1\n")
+	run("commit", "-q", "-am", "change a")
+
+	writeFile(t, filepath.Join(dir, "b.go"), "package a\n\nvar B = 1\n")
+	run("add", "b.go")
+	writeFile(t, filepath.Join(dir, "sub", "c.go"), "package sub\n")
+
+	// the temp dir can be behind a symlink, git reports the resolved path
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		staged   bool
+		base     string
+		expected []string
+	}{
+		{
+			name:     "working tree",
+			expected: []string{filepath.Join(root, "b.go"), filepath.Join(root, "sub", "c.go")},
+		},
+		{
+			name:     "staged",
+			staged:   true,
+			expected: []string{filepath.Join(root, "b.go")},
+		},
+		{
+			name:     "against a ref",
+			base:     "main",
+			expected: []string{filepath.Join(root, "a.go"), filepath.Join(root, "b.go"), filepath.Join(root, "sub", "c.go")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := changedFiles(dir, tt.staged, tt.base)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.expected, files)
+		})
+	}
+}
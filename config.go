@@ -16,12 +16,33 @@ import (
 type Config struct {
 	// ColorizeOutput toggles colorized output. Ex red for fail and green for pass.
 	ColorizeOutput bool
+	// FuzzTime is passed to -fuzztime when fuzzing. Ex 30s or 1000x.
+	FuzzTime string
+	// FuzzMinimizeTime is passed to -fuzzminimizetime when fuzzing.
+	FuzzMinimizeTime string
+	// BuildTags is a comma separated list of build tags used to find and run tests. Ex integration,e2e
+	BuildTags string
+	// HelperDepth is how many nested helper functions are followed when looking for t.Run calls.
+	HelperDepth int
+	// WatchDebounce is how long -w waits for files to stop changing before running tests. Ex 200ms.
+	WatchDebounce string
+	// ExtraFlags are added to every go test command, before any given after --. Ex -race -count=1
+	ExtraFlags string
+	// HangTimeout is how long the tests can write nothing before their goroutines are dumped, off when empty. Ex 1m
+	HangTimeout string
+	// ProjectFlags are added to the go test commands of the module at a directory, set one per line.
+	// Ex ProjectFlags[~/src/api] = -tags=integration -timeout=5m
+	ProjectFlags map[string]string
 }
 
 // config contains the default configuration for the program.
 // This can be overridden by a config file.
 var globalConfig = &Config{
-	ColorizeOutput: true, // Default to on for colorized output.
+	ColorizeOutput:   true,  // Default to on for colorized output.
+	FuzzTime:         "30s", // Fuzz tests run forever by default, keep them short unless configured.
+	FuzzMinimizeTime: "60s",
+	HelperDepth:      3,
+	WatchDebounce:    "200ms", // Editors and formatters often write several times on save.
 }
 
 type configOptions struct {
@@ -107,15 +128,21 @@ func loadConfig(config *Config, cOpts ...ConfigOption) error {
 		case txt[0] == '#' || txt == "":
 			continue
 		default:
-			// parse the line
-			parts := strings.Split(txt, "=")
-			if len(parts) != 2 {
+			// parse the line, values can contain = IE ExtraFlags=-count=1
+			key, val, ok := strings.Cut(txt, "=")
+			if !ok {
 				fmt.Printf("Invalid format on line %d\n", line)
 				continue
 			}
 
-			key := strings.TrimSpace(parts[0])
-			val := strings.TrimSpace(parts[1])
+			key = strings.TrimSpace(key)
+			val = strings.TrimSpace(val)
+
+			// map settings are keyed with brackets, IE ProjectFlags[~/src/api]
+			var mapKey string
+			if name, rest, ok := strings.Cut(key, "["); ok && strings.HasSuffix(rest, "]") {
+				key, mapKey = name, strings.TrimSuffix(rest, "]")
+			}
 
 			f := elem.FieldByName(key)
 
@@ -133,6 +160,27 @@ func loadConfig(config *Config, cOpts ...ConfigOption) error {
 				}
 
 				f.SetBool(b)
+			case reflect.String:
+				f.SetString(val)
+			case reflect.Int:
+				i, err := strconv.Atoi(val)
+				if err != nil {
+					fmt.Printf("Invalid int value '%s' for %s\n", val, key)
+					continue
+				}
+
+				f.SetInt(int64(i))
+			case reflect.Map:
+				if mapKey == "" {
+					fmt.Printf("Missing key for %s, set it as %s[key]\n", key, key)
+					continue
+				}
+
+				if f.IsNil() {
+					f.Set(reflect.MakeMap(f.Type()))
+				}
+
+				f.SetMapIndex(reflect.ValueOf(mapKey), reflect.ValueOf(val))
 			}
 		}
 	}
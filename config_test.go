@@ -21,6 +21,26 @@ func Test_loadConfig(t *testing.T) {
 			In:       "# Some Comment.\n ColorizeOutput=true\n",
 			Expected: &Config{ColorizeOutput: true},
 		},
+		{
+			Name:     "string field",
+			In:       "FuzzTime = 1m\nFuzzMinimizeTime=100x",
+			Expected: &Config{FuzzTime: "1m", FuzzMinimizeTime: "100x"},
+		},
+		{
+			Name:     "int field",
+			In:       "HelperDepth=5",
+			Expected: &Config{HelperDepth: 5},
+		},
+		{
+			Name:     "value with equals",
+			In:       "ExtraFlags = -count=1 -race",
+			Expected: &Config{ExtraFlags: "-count=1 -race"},
+		},
+		{
+			Name:     "map field",
+			In:       "ProjectFlags[~/src/api] = -tags=integration\nProjectFlags[/src/web]=-short\nProjectFlags=-v",
+			Expected: &Config{ProjectFlags: map[string]string{"~/src/api": "-tags=integration", "/src/web": "-short"}},
+		},
 		{
 			Name:     "basic unknown field",
 			In:       "SomeNewField=true",
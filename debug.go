@@ -4,14 +4,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 )
 
-func debugTest(t Test, path, modRoot string) (exec.Cmd, bool) {
-	p, err := exec.LookPath("dlv")
-	if err != nil {
-		panic(err)
-	}
-
+func debugTest(t Test, path, modRoot string) (exec.Cmd, runOutcome) {
 	// Create a temp file to set breakpoints and tell dlv to continue.
 	tempFile, err := os.CreateTemp("", "go-test_*")
 	if err != nil {
@@ -26,27 +22,79 @@ func debugTest(t Test, path, modRoot string) (exec.Cmd, bool) {
 		}
 	}()
 
-	tempFile.Write([]byte("b " + fmt.Sprintf("%s:%d", packageFromPathAndMod(t.FilePath, modRoot), t.LineNumber) + "\n"))
+	file, line, cond := breakpoint(t)
+	tempFile.Write([]byte("b " + fmt.Sprintf("%s:%d", packageFromPathAndMod(file, modRoot), line) + "\n"))
+	if cond != "" {
+		tempFile.Write([]byte("cond 1 " + cond + "\n"))
+	}
+
 	tempFile.Write([]byte("c\n"))
 	tempFile.Close()
 
-	args := []string{"dlv", "test", "--init", tempFile.Name(), resolvePackage(modRoot, path), "--", "-test.run", t.Name}
-	fmt.Println("Running test with debugger:", args)
+	cmd := debugCommand(t, path, modRoot, "--init", tempFile.Name())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
 
-	cmd := exec.Cmd{
-		Path:   p,
-		Env:    os.Environ(),
-		Args:   args,
-		Dir:    modRoot,
-		Stdin:  os.Stdin,
-		Stdout: os.Stdout,
-		Stderr: os.Stderr,
-	}
+	fmt.Println("Running test with debugger:", cmd.Args)
 
 	err = cmd.Run()
 	if err != nil {
 		panic(err)
 	}
 
-	return cmd, true
+	return cmd, runOutcome{Pass: true}
+}
+
+// breakpoint returns where the debugger stops for t and the condition it stops on, if any. Subtests stop inside
+// their closure, since table rows share a closure only stop when the selected row runs.
+func breakpoint(t Test) (string, int, string) {
+	if t.BodyLineNumber == 0 {
+		return t.FilePath, t.LineNumber, ""
+	}
+
+	var cond string
+	if t.TestingParam != "" {
+		cond = fmt.Sprintf("%s.common.name == %q", t.TestingParam, t.Name)
+	}
+
+	return t.File, t.BodyLineNumber, cond
+}
+
+// debugCommand returns the dlv test command debugging t, dlvFlags are added before the package to debug.
+func debugCommand(t Test, path, modRoot string, dlvFlags ...string) exec.Cmd {
+	p, err := exec.LookPath("dlv")
+	if err != nil {
+		panic(err)
+	}
+
+	// the extra go test arguments are split between the build and the test binary dlv runs
+	extraBuildFlags, extraTestFlags := dlvArgs(extraArgs(modRoot))
+
+	args := append([]string{"dlv", "test"}, dlvFlags...)
+	args = append(args, resolvePackage(modRoot, path))
+	if flags := append(buildFlags(), extraBuildFlags...); len(flags) > 0 {
+		args = append(args, "--build-flags="+strings.Join(flags, " "))
+	}
+
+	var testFlags []string
+	if t.Name != "" {
+		testFlags = append(testFlags, "-test.run", testRunPattern(t))
+		if t.SuiteMethod != "" {
+			testFlags = append(testFlags, "-testify.m", runPattern(t.SuiteMethod))
+		}
+	}
+
+	testFlags = append(testFlags, extraTestFlags...)
+	if len(testFlags) > 0 {
+		args = append(args, "--")
+		args = append(args, testFlags...)
+	}
+
+	return exec.Cmd{
+		Path: p,
+		Env:  os.Environ(),
+		Args: args,
+		Dir:  modRoot,
+	}
 }
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// wildcardMarker stands in for the parts of a subtest name that can't be resolved until it's replaced with *.
+const wildcardMarker = "\x00"
+
+// wildcardArg formats as the wildcard marker for any verb so unknown fmt.Sprintf arguments become wildcards.
+type wildcardArg struct{}
+
+func (wildcardArg) Format(f fmt.State, _ rune) {
+	f.Write([]byte(wildcardMarker))
+}
+
+// tableRow is a single entry of a table being ranged over.
+type tableRow struct {
+	// key is the key of a map entry, nil for slices and arrays.
+	key   ast.Expr
+	index int
+	value ast.Expr
+	pos   token.Pos
+	end   token.Pos
+}
+
+// rowBinding binds the variables of a range statement to one row of its table.
+type rowBinding struct {
+	rng *ast.RangeStmt
+	row tableRow
+}
+
+// subtestCase evaluates a subtest name, names that can't be resolved at all are skipped rather than turned into a
+// wildcard that would match every subtest.
+func (tp *testPackage) subtestCase(expr ast.Expr, scope *helperScope, bind *rowBinding, pos,
+	end token.Pos) (subtestCase, bool) {
+	name, ok := tp.evalName(expr, scope, bind, 0).(string)
+	if !ok || strings.Trim(name, wildcardMarker) == "" {
+		return subtestCase{}, false
+	}
+
+	return subtestCase{
+		name:     strings.ReplaceAll(name, wildcardMarker, "*"),
+		pos:      pos,
+		end:      end,
+		wildcard: strings.Contains(name, wildcardMarker),
+	}, true
+}
+
+// findRange returns the first range statement whose variables are used to build expr.
+func (tp *testPackage) findRange(expr ast.Expr, scope *helperScope, depth int) *ast.RangeStmt {
+	if depth > maxResolveDepth {
+		return nil
+	}
+
+	var rng *ast.RangeStmt
+	ast.Inspect(expr, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if rng != nil || !ok {
+			return rng == nil
+		}
+
+		// parameters of helpers are built from the caller's arguments
+		if resolved := scope.resolve(tp, ident); resolved != ident {
+			rng = tp.findRange(resolved, scope, depth+1)
+			return false
+		}
+
+		obj := tp.TypesInfo.ObjectOf(ident)
+		if found, _ := tp.rangeOf(obj); found != nil {
+			rng = found
+			return false
+		}
+
+		// locals like name := fmt.Sprintf("case-%d", i)
+		if value, ok := tp.values[obj]; ok {
+			if _, isVar := obj.(*types.Var); isVar && obj.Parent() != obj.Pkg().Scope() {
+				rng = tp.findRange(value, scope, depth+1)
+			}
+		}
+
+		return false
+	})
+
+	return rng
+}
+
+// rangeRows returns the rows of the table rng ranges over.
+func (tp *testPackage) rangeRows(rng *ast.RangeStmt) []tableRow {
+	var rows []tableRow
+
+	// range over an integer, IE for i := range 3
+	if tv, ok := tp.TypesInfo.Types[rng.X]; ok && tv.Value != nil && tv.Value.Kind() == constant.Int {
+		n, _ := constant.Int64Val(tv.Value)
+		for i := 0; i < int(n) && i < maxIntRange; i++ {
+			rows = append(rows, tableRow{index: i, pos: rng.Pos(), end: rng.End()})
+		}
+
+		return rows
+	}
+
+	lit := tp.resolveComposite(rng.X, 0)
+	if lit == nil {
+		return nil
+	}
+
+	var isMap bool
+	if typ := tp.TypesInfo.TypeOf(lit); typ != nil {
+		_, isMap = typ.Underlying().(*types.Map)
+	}
+
+	for i, el := range lit.Elts {
+		row := tableRow{index: i, value: el, pos: el.Pos(), end: el.End()}
+		if kv, ok := el.(*ast.KeyValueExpr); ok {
+			row.value = kv.Value
+			if isMap {
+				row.key = kv.Key
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// maxIntRange limits how many subtests are listed for loops over an integer.
+const maxIntRange = 100
+
+// evalName evaluates an expression used to build a subtest name. The result is a string, int64, float64 or bool,
+// with any part that couldn't be resolved replaced by the wildcard marker, or wildcardArg when nothing is known.
+func (tp *testPackage) evalName(expr ast.Expr, scope *helperScope, bind *rowBinding, depth int) any {
+	if depth > maxResolveDepth {
+		return wildcardArg{}
+	}
+
+	expr = scope.resolve(tp, expr)
+
+	if tv, ok := tp.TypesInfo.Types[expr]; ok && tv.Value != nil {
+		return constantValue(tv.Value)
+	}
+
+	switch x := expr.(type) {
+	case *ast.BasicLit:
+		if s, err := strconv.Unquote(x.Value); err == nil && x.Kind == token.STRING {
+			return s
+		}
+
+	case *ast.ParenExpr:
+		return tp.evalName(x.X, scope, bind, depth+1)
+
+	case *ast.Ident:
+		obj := tp.TypesInfo.ObjectOf(x)
+		if bind != nil {
+			if rng, v := tp.rangeOf(obj); rng == bind.rng {
+				return tp.evalRangeVar(rng, v, bind.row, depth)
+			}
+		}
+
+		if value, ok := tp.values[obj]; ok {
+			return tp.evalName(value, scope, bind, depth+1)
+		}
+
+	case *ast.SelectorExpr:
+		// a field of the current row, IE tt.name
+		row := tp.boundRow(x.X, scope, bind, depth)
+		if row == nil {
+			break
+		}
+
+		if value := tp.fieldValue(row, x.Sel.Name); value != nil {
+			return tp.evalName(value, nil, nil, depth+1)
+		}
+
+	case *ast.BinaryExpr:
+		if x.Op != token.ADD {
+			break
+		}
+
+		left, lok := toNamePart(tp.evalName(x.X, scope, bind, depth+1))
+		right, rok := toNamePart(tp.evalName(x.Y, scope, bind, depth+1))
+		if lok && rok {
+			return left + right
+		}
+
+	case *ast.CallExpr:
+		return tp.evalCall(x, scope, bind, depth)
+	}
+
+	return wildcardArg{}
+}
+
+// evalRangeVar returns the value of the key or value variable of rng for a row.
+func (tp *testPackage) evalRangeVar(rng *ast.RangeStmt, v types.Object, row tableRow, depth int) any {
+	if key, ok := rng.Key.(*ast.Ident); ok && tp.TypesInfo.ObjectOf(key) == v {
+		if row.key != nil {
+			return tp.evalName(row.key, nil, nil, depth+1)
+		}
+
+		return int64(row.index)
+	}
+
+	if row.value == nil {
+		return wildcardArg{}
+	}
+
+	return tp.evalName(row.value, nil, nil, depth+1)
+}
+
+// boundRow returns the struct literal of the row expr refers to, either the range value or an index into the table.
+func (tp *testPackage) boundRow(expr ast.Expr, scope *helperScope, bind *rowBinding, depth int) *ast.CompositeLit {
+	if bind == nil {
+		return nil
+	}
+
+	switch x := scope.resolve(tp, expr).(type) {
+	case *ast.Ident:
+		rng, v := tp.rangeOf(tp.TypesInfo.ObjectOf(x))
+		if rng != bind.rng || bind.row.value == nil {
+			return nil
+		}
+
+		if value, ok := rng.Value.(*ast.Ident); !ok || tp.TypesInfo.ObjectOf(value) != v {
+			return nil
+		}
+
+		return tp.resolveComposite(bind.row.value, 0)
+
+	case *ast.IndexExpr:
+		// IE for i := range tests { t.Run(tests[i].name, ...) }
+		index, ok := tp.evalName(x.Index, scope, bind, depth+1).(int64)
+		if !ok {
+			return nil
+		}
+
+		table := tp.resolveComposite(x.X, 0)
+		if table == nil || int(index) >= len(table.Elts) {
+			return nil
+		}
+
+		return tp.resolveComposite(table.Elts[index], 0)
+	}
+
+	return nil
+}
+
+// evalCall evaluates the calls commonly used to build names, fmt.Sprintf, fmt.Sprint, strconv.Itoa and string
+// conversions.
+func (tp *testPackage) evalCall(c *ast.CallExpr, scope *helperScope, bind *rowBinding, depth int) any {
+	if tv, ok := tp.TypesInfo.Types[c.Fun]; ok && tv.IsType() && len(c.Args) == 1 {
+		return tp.evalName(c.Args[0], scope, bind, depth+1)
+	}
+
+	var fnIdent *ast.Ident
+	switch fn := c.Fun.(type) {
+	case *ast.Ident:
+		fnIdent = fn
+	case *ast.SelectorExpr:
+		fnIdent = fn.Sel
+	default:
+		return wildcardArg{}
+	}
+
+	fn, ok := tp.TypesInfo.Uses[fnIdent].(*types.Func)
+	if !ok {
+		return wildcardArg{}
+	}
+
+	args := make([]any, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = tp.evalName(arg, scope, bind, depth+1)
+	}
+
+	switch fn.FullName() {
+	case "fmt.Sprintf":
+		format, ok := args[0].(string)
+		if !ok || c.Ellipsis.IsValid() {
+			return wildcardArg{}
+		}
+
+		return fmt.Sprintf(format, args[1:]...)
+	case "fmt.Sprint":
+		if c.Ellipsis.IsValid() {
+			return wildcardArg{}
+		}
+
+		return fmt.Sprint(args...)
+	case "strconv.Itoa":
+		return fmt.Sprint(args[0])
+	}
+
+	return wildcardArg{}
+}
+
+// toNamePart converts a value to the string it contributes to a concatenated name.
+func toNamePart(v any) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case wildcardArg:
+		return wildcardMarker, true
+	}
+
+	return "", false
+}
+
+func constantValue(v constant.Value) any {
+	switch v.Kind() {
+	case constant.String:
+		return constant.StringVal(v)
+	case constant.Int:
+		i, _ := constant.Int64Val(v)
+		return i
+	case constant.Float:
+		f, _ := constant.Float64Val(v)
+		return f
+	case constant.Bool:
+		return constant.BoolVal(v)
+	}
+
+	return wildcardArg{}
+}
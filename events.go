@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// testEvent is a single event of the go test -json stream, see go doc test2json.
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+	// ImportPath is set on build-output events and FailedBuild on the fail event of a package that didn't build.
+	ImportPath  string
+	FailedBuild string
+}
+
+// packageResult is the outcome of a single package in a run.
+type packageResult struct {
+	Package string
+	Action  string
+	Elapsed float64
+}
+
+// failedTest is a failed test along with everything it logged.
+type failedTest struct {
+	Package string
+	Test    string
+	Elapsed float64
+	Output  []string
+}
+
+// testEventWriter decodes the go test -json stream written to it and renders a status line for every test to out,
+// colored by the test's result. Once the run is done PrintSummary totals up the results.
+type testEventWriter struct {
+	out     io.Writer
+	partial []byte
+	// output holds what each running test has logged, it's printed when the test fails in quiet mode and kept for
+	// the summary.
+	output map[string][]string
+	// seen maps each package to the tests run in it and when they were run.
+	seen map[string]map[string]time.Time
+	// running holds the run event of every test that hasn't reported a result yet, keyed by package and test.
+	running map[string]testEvent
+	// results maps each package to the result of every test in it, the package's own result is under "".
+	results map[string]map[string]string
+	// elapsed is how long each test took, keyed by package and test.
+	elapsed map[string]float64
+	// shuffleSeed is the seed printed by the test binary when run with -shuffle.
+	shuffleSeed string
+	// counts is the number of tests for each result, IE pass, fail and skip.
+	counts   map[string]int
+	packages []packageResult
+	failed   []failedTest
+	// junit records the run for the -junit report.
+	junit *junitReport
+	// races are the data races reported during the run, raceLines holds the report each test is in the middle of
+	// writing.
+	races     []RaceReport
+	raceLines map[string][]string
+	// capturing is set once the tests are sent SIGQUIT for hanging, from then on their output is the goroutine
+	// dump which is kept in dump rather than printed.
+	capturing atomic.Bool
+	dump      []string
+	// listener is given every event as it's decoded, see testEventListener.
+	listener func(testEvent)
+}
+
+// testEventListener is given every event of the runs from now on, serve streams them to the editor with it.
+var testEventListener func(testEvent)
+
+func newTestEventWriter(out io.Writer) *testEventWriter {
+	var junit *junitReport
+	if *junitPath != "" {
+		junit = newJUnitReport()
+	}
+
+	return &testEventWriter{
+		out:       out,
+		output:    map[string][]string{},
+		seen:      map[string]map[string]time.Time{},
+		running:   map[string]testEvent{},
+		results:   map[string]map[string]string{},
+		elapsed:   map[string]float64{},
+		counts:    map[string]int{},
+		junit:     junit,
+		raceLines: map[string][]string{},
+		listener:  testEventListener,
+	}
+}
+
+// Write decodes every complete line of p, a partial line is kept until the rest of it is written.
+func (w *testEventWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.handleLine(w.partial[:i+1])
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush handles anything left after the last newline, it should be called once the command has exited. When the
+// tests were quit for hanging the ones still running never report a result, they're failed here instead.
+func (w *testEventWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.handleLine(w.partial)
+		w.partial = nil
+	}
+
+	if w.capturing.Load() {
+		w.failRunning()
+	}
+}
+
+// failRunning fails every test that's still running and the packages they're in, subtests before their parents
+// like go test does.
+func (w *testEventWriter) failRunning() {
+	running := slices.SortedFunc(maps.Values(w.running), func(a, b testEvent) int {
+		if a.Package != b.Package {
+			return strings.Compare(a.Package, b.Package)
+		}
+
+		return strings.Compare(b.Test, a.Test)
+	})
+
+	now := time.Now()
+	started := map[string]time.Time{}
+	for _, ev := range running {
+		w.handleEvent(testEvent{Time: now, Action: "fail", Package: ev.Package, Test: ev.Test,
+			Elapsed: now.Sub(ev.Time).Seconds()})
+
+		if first, ok := started[ev.Package]; !ok || ev.Time.Before(first) {
+			started[ev.Package] = ev.Time
+		}
+	}
+
+	for _, pkg := range slices.Sorted(maps.Keys(started)) {
+		if _, ok := w.results[pkg][""]; !ok {
+			w.handleEvent(testEvent{Time: now, Action: "fail", Package: pkg, Elapsed: now.Sub(started[pkg]).Seconds()})
+		}
+	}
+}
+
+func (w *testEventWriter) handleLine(line []byte) {
+	var ev testEvent
+	if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &ev) != nil {
+		if w.capturing.Load() {
+			w.dump = append(w.dump, string(line))
+			return
+		}
+
+		// anything that isn't an event, IE output of a test binary that failed to start, is passed through
+		w.out.Write(line)
+		return
+	}
+
+	w.handleEvent(ev)
+}
+
+func (w *testEventWriter) handleEvent(ev testEvent) {
+	if w.listener != nil {
+		w.listener(ev)
+	}
+
+	if w.junit != nil {
+		w.junit.add(ev)
+	}
+
+	key := ev.Package + " " + ev.Test
+
+	switch ev.Action {
+	case "run":
+		if ev.Test == "" {
+			return
+		}
+
+		if w.seen[ev.Package] == nil {
+			w.seen[ev.Package] = map[string]time.Time{}
+		}
+
+		seenAt := ev.Time
+		if seenAt.IsZero() {
+			seenAt = time.Now()
+		}
+
+		w.seen[ev.Package][ev.Test] = seenAt
+
+		ev.Time = seenAt
+		w.running[key] = ev
+
+	case "output":
+		// the status lines go test writes are rendered from the results instead
+		if isStatusOutput(ev.Output) {
+			return
+		}
+
+		if w.capturing.Load() {
+			w.dump = append(w.dump, ev.Output)
+			return
+		}
+
+		// race reports are collected to be summarized instead of printed as is
+		if lines, inRace := w.raceLines[key]; inRace {
+			if ev.Output == raceDelimiter {
+				w.addRace(ev.Package, ev.Test, lines)
+				delete(w.raceLines, key)
+			} else {
+				w.raceLines[key] = append(lines, ev.Output)
+			}
+
+			return
+		}
+
+		if ev.Output == raceDelimiter {
+			w.raceLines[key] = nil
+			return
+		}
+
+		if ev.Test == "" {
+			if seed, ok := strings.CutPrefix(ev.Output, "-test.shuffle "); ok {
+				w.shuffleSeed = strings.TrimSpace(seed)
+			}
+
+			io.WriteString(w.out, ev.Output)
+			return
+		}
+
+		if !*quiet {
+			io.WriteString(w.out, ev.Output)
+		}
+
+		// the lines announcing which test is running only make sense in the stream
+		if !strings.HasPrefix(ev.Output, "=== ") {
+			w.output[key] = append(w.output[key], ev.Output)
+		}
+
+	case "build-output":
+		io.WriteString(w.out, ev.Output)
+
+	case "pass", "fail", "skip":
+		if w.results[ev.Package] == nil {
+			w.results[ev.Package] = map[string]string{}
+		}
+
+		w.results[ev.Package][ev.Test] = ev.Action
+		w.elapsed[key] = ev.Elapsed
+		delete(w.running, key)
+
+		if ev.Test == "" {
+			w.packages = append(w.packages, packageResult{Package: ev.Package, Action: ev.Action, Elapsed: ev.Elapsed})
+			return
+		}
+
+		w.counts[ev.Action]++
+
+		// quiet mode only shows failures, like go test without -v
+		if !*quiet || ev.Action == "fail" {
+			status := fmt.Sprintf("--- %s: %s (%.2fs)", strings.ToUpper(ev.Action), ev.Test, ev.Elapsed)
+			fmt.Fprintln(w.out, strings.Repeat("    ", strings.Count(ev.Test, "/"))+resultColor(ev.Action, status))
+		}
+
+		if ev.Action == "fail" {
+			if *quiet {
+				for _, output := range w.output[key] {
+					io.WriteString(w.out, output)
+				}
+			}
+
+			w.failed = append(w.failed, failedTest{Package: ev.Package, Test: ev.Test, Elapsed: ev.Elapsed,
+				Output: w.output[key]})
+		}
+
+		delete(w.output, key)
+	}
+}
+
+// captureDump makes the writer collect the output as a goroutine dump from now on, see reportHang.
+func (w *testEventWriter) captureDump() {
+	w.capturing.Store(true)
+}
+
+// takeResults returns the results recorded so far and starts recording anew.
+func (w *testEventWriter) takeResults() map[string]map[string]string {
+	results := w.results
+	w.results = map[string]map[string]string{}
+
+	return results
+}
+
+// PrintSummary prints the result of every package, the number of tests passed, failed and skipped, and the
+// output of each failed test.
+func (w *testEventWriter) PrintSummary() {
+	if len(w.packages) == 0 && len(w.counts) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "Summary:")
+	for _, pkg := range w.packages {
+		switch pkg.Action {
+		case "pass":
+			fmt.Fprintln(w.out, resultColor(pkg.Action, fmt.Sprintf("ok  \t%s\t%.3fs", pkg.Package, pkg.Elapsed)))
+		case "fail":
+			fmt.Fprintln(w.out, resultColor(pkg.Action, fmt.Sprintf("FAIL\t%s\t%.3fs", pkg.Package, pkg.Elapsed)))
+		case "skip":
+			fmt.Fprintf(w.out, "?   \t%s\t[no test files]\n", pkg.Package)
+		}
+	}
+
+	fmt.Fprintf(w.out, "%s, %s, %s\n", w.count("pass", "passed"), w.count("fail", "failed"), w.count("skip", "skipped"))
+
+	failed := w.failedLeaves()
+	if len(failed) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "Failed tests:")
+	for _, test := range failed {
+		fmt.Fprintln(w.out, resultColor("fail", fmt.Sprintf("--- FAIL: %s (%.2fs) %s", test.Test, test.Elapsed, test.Package)))
+		for _, output := range test.Output {
+			io.WriteString(w.out, output)
+		}
+	}
+}
+
+// count returns the number of tests with the result, colored when there are any.
+func (w *testEventWriter) count(action, label string) string {
+	s := fmt.Sprintf("%d %s", w.counts[action], label)
+	if w.counts[action] == 0 {
+		return s
+	}
+
+	return resultColor(action, s)
+}
+
+// failedLeaves returns the failed tests without the parents that only failed because one of their subtests did.
+func (w *testEventWriter) failedLeaves() []failedTest {
+	var leaves []failedTest
+	for _, test := range w.failed {
+		hasFailedSubtest := false
+		for _, other := range w.failed {
+			if other.Package == test.Package && strings.HasPrefix(other.Test, test.Test+"/") {
+				hasFailedSubtest = true
+				break
+			}
+		}
+
+		if !hasFailedSubtest || len(test.Output) > 0 {
+			leaves = append(leaves, test)
+		}
+	}
+
+	return leaves
+}
+
+// isStatusOutput reports if output is one of the lines go test writes with the result of a test or package.
+func isStatusOutput(output string) bool {
+	trimmed := strings.TrimLeft(output, " ")
+	for _, prefix := range []string{"--- PASS:", "--- FAIL:", "--- SKIP:", "ok  \t", "FAIL\t", "?   \t"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+
+	return trimmed == "PASS\n" || trimmed == "FAIL\n"
+}
+
+// resultColor colors s green for pass, red for fail and yellow for skip when colorized output is enabled.
+func resultColor(action, s string) string {
+	if !globalConfig.ColorizeOutput {
+		return s
+	}
+
+	switch action {
+	case "pass":
+		return "\033[32m" + s + "\033[0m"
+	case "fail":
+		return "\033[31m" + s + "\033[0m"
+	case "skip":
+		return "\033[33m" + s + "\033[0m"
+	}
+
+	return s
+}
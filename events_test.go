@@ -0,0 +1,140 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_testEventWriter(t *testing.T) {
+	stream := `{"Action":"start","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestA/dynamic-42"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA/dynamic-42","Output":"=== RUN   TestA/dynamic-42\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA/dynamic-42","Output":"    a_test.go:9: FAIL is only a log line\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA/dynamic-42","Output":"    --- FAIL: TestA/dynamic-42 (0.00s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestA/dynamic-42","Elapsed":0.01}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"--- FAIL: TestA (0.01s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestA","Elapsed":0.01}
+{"Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestB","Output":"--- PASS: TestB (0.00s)\n"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestB"}
+{"Action":"output","Package":"example.com/pkg","Output":"FAIL\n"}
+{"Action":"output","Package":"example.com/pkg","Output":"FAIL\texample.com/pkg\t0.015s\n"}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.015}
+{"Action":"skip","Package":"example.com/empty"}
+not an event
+`
+
+	summary := "\nSummary:\n" +
+		"FAIL\texample.com/pkg\t0.015s\n" +
+		"?   \texample.com/empty\t[no test files]\n" +
+		"1 passed, 2 failed, 0 skipped\n" +
+		"\nFailed tests:\n" +
+		"--- FAIL: TestA/dynamic-42 (0.01s) example.com/pkg\n" +
+		"    a_test.go:9: FAIL is only a log line\n"
+
+	tests := []struct {
+		name     string
+		quiet    bool
+		expected string
+	}{
+		{
+			name: "verbose",
+			expected: "=== RUN   TestA\n" +
+				"=== RUN   TestA/dynamic-42\n" +
+				"    a_test.go:9: FAIL is only a log line\n" +
+				"    --- FAIL: TestA/dynamic-42 (0.01s)\n" +
+				"--- FAIL: TestA (0.01s)\n" +
+				"--- PASS: TestB (0.00s)\n" +
+				"not an event\n" + summary,
+		},
+		{
+			name:  "quiet only shows failures",
+			quiet: true,
+			expected: "    --- FAIL: TestA/dynamic-42 (0.01s)\n" +
+				"    a_test.go:9: FAIL is only a log line\n" +
+				"--- FAIL: TestA (0.01s)\n" +
+				"not an event\n" + summary,
+		},
+	}
+
+	colorize := globalConfig.ColorizeOutput
+	t.Cleanup(func() {
+		globalConfig.ColorizeOutput = colorize
+		quiet = boolPtr(false)
+	})
+	globalConfig.ColorizeOutput = false
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quiet = boolPtr(tt.quiet)
+
+			var out strings.Builder
+			w := newTestEventWriter(&out)
+
+			// write in small chunks so events are split across writes
+			for i := 0; i < len(stream); i += 7 {
+				w.Write([]byte(stream[i:min(i+7, len(stream))]))
+			}
+			w.Flush()
+			w.PrintSummary()
+
+			assert.Equal(t, tt.expected, out.String())
+			assert.Equal(t, []string{"TestA", "TestA/dynamic-42", "TestB"}, slices.Sorted(maps.Keys(w.seen["example.com/pkg"])))
+		})
+	}
+}
+
+func Test_testEventWriterHang(t *testing.T) {
+	colorize := globalConfig.ColorizeOutput
+	t.Cleanup(func() { globalConfig.ColorizeOutput = colorize })
+	globalConfig.ColorizeOutput = false
+	quiet = boolPtr(true)
+	t.Cleanup(func() { quiet = boolPtr(false) })
+
+	var out strings.Builder
+	w := newTestEventWriter(&out)
+	w.Write([]byte(`{"Action":"run","Package":"example.com/pkg","Test":"TestDone"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestDone"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestHang"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestHang/sub"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestHang/sub","Output":"    hang_test.go:9: waiting\n"}
+`))
+
+	// the tests are quit and dump their goroutines instead of reporting a result
+	w.captureDump()
+	w.Write([]byte("SIGQUIT: quit\ngoroutine 1 [chan receive]:\n"))
+	w.Flush()
+	w.PrintSummary()
+
+	assert.Equal(t, "    --- FAIL: TestHang/sub (0.00s)\n"+
+		"    hang_test.go:9: waiting\n"+
+		"--- FAIL: TestHang (0.00s)\n"+
+		"\nSummary:\n"+
+		"FAIL\texample.com/pkg\t0.000s\n"+
+		"1 passed, 2 failed, 0 skipped\n"+
+		"\nFailed tests:\n"+
+		"--- FAIL: TestHang/sub (0.00s) example.com/pkg\n"+
+		"    hang_test.go:9: waiting\n", out.String())
+	assert.Equal(t, map[string]string{"": "fail", "TestDone": "pass", "TestHang": "fail", "TestHang/sub": "fail"},
+		w.results["example.com/pkg"])
+	assert.Equal(t, []string{"SIGQUIT: quit\n", "goroutine 1 [chan receive]:\n"}, w.dump)
+}
+
+func Test_resultColor(t *testing.T) {
+	colorize := globalConfig.ColorizeOutput
+	t.Cleanup(func() { globalConfig.ColorizeOutput = colorize })
+
+	globalConfig.ColorizeOutput = true
+	assert.Equal(t, "\033[31m--- FAIL: TestA\033[0m", resultColor("fail", "--- FAIL: TestA"))
+	assert.Equal(t, "\033[32mok\033[0m", resultColor("pass", "ok"))
+	assert.Equal(t, "output", resultColor("output", "output"))
+
+	globalConfig.ColorizeOutput = false
+	assert.Equal(t, "--- FAIL: TestA", resultColor("fail", "--- FAIL: TestA"))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// findExamples returns the examples in the file that go test runs, IE the ones with an // Output: comment.
+func (tp *testPackage) findExamples(f *ast.File, path string) []Test {
+	var tests []Test
+
+	for _, ex := range doc.Examples(f) {
+		// examples without an output comment are compiled but never run
+		if ex.Output == "" && !ex.EmptyOutput {
+			continue
+		}
+
+		tests = append(tests, Test{
+			File:           path,
+			Name:           "Example" + ex.Name,
+			IsExample:      true,
+			FilePath:       path,
+			LineNumber:     tp.Fset.Position(ex.Code.Pos()).Line,
+			ExpectedOutput: ex.Output,
+		})
+	}
+
+	return tests
+}
+
+// runExample runs the example and shows the got and want output of any failing examples side by side.
+func runExample(t Test) (exec.Cmd, bool) {
+	path, modRoot := testToPathAndRoot(t)
+
+	p, err := exec.LookPath("go")
+	if err != nil {
+		panic(err)
+	}
+
+	args := []string{"test", quietMode(), path}
+	args = append(args, buildFlags()...)
+
+	if t.Name != "" {
+		args = append(args, "-run", runPattern(t.Name))
+	}
+
+	args = append(args, extraArgs(modRoot)...)
+
+	// capture the output so failures can be rendered after the run
+	exampleBuffer := &bytes.Buffer{}
+
+	cmd := exec.Cmd{
+		Path:   p,
+		Env:    os.Environ(),
+		Args:   append([]string{"go"}, args...),
+		Dir:    modRoot,
+		Stdout: io.MultiWriter(os.Stdout, exampleBuffer),
+		Stderr: os.Stderr,
+	}
+
+	fmt.Println("Running", cmd.Args, "@", cmd.Dir)
+
+	var pass bool
+	err = cmd.Run()
+	var exit *exec.ExitError
+	switch {
+	case err == nil:
+		pass = true
+	case errors.As(err, &exit):
+		for _, failure := range parseExampleFailures(exampleBuffer) {
+			printSideBySide(os.Stdout, failure)
+		}
+	default:
+		panic(err)
+	}
+
+	return cmd, pass
+}
+
+// exampleFailure is the output of a failed example.
+type exampleFailure struct {
+	Name string
+	Got  []string
+	Want []string
+}
+
+// parseExampleFailures finds the got/want blocks go test prints for failed examples. IE
+//
+//	--- FAIL: ExampleHello (0.00s)
+//	got:
+//	hello
+//	want:
+//	world
+func parseExampleFailures(r io.Reader) []exampleFailure {
+	var failures []exampleFailure
+	var current *exampleFailure
+	var block *[]string
+
+	scnr := bufio.NewScanner(r)
+	for scnr.Scan() {
+		line := scnr.Text()
+
+		switch {
+		case strings.HasPrefix(line, "--- FAIL: Example"):
+			failures = append(failures, exampleFailure{Name: strings.Fields(line)[2]})
+			current = &failures[len(failures)-1]
+			block = nil
+		case current == nil:
+			continue
+		case line == "got:":
+			block = &current.Got
+		case line == "want:":
+			block = &current.Want
+		case strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "=== ") || line == "FAIL" || strings.HasPrefix(line, "FAIL\t"):
+			current, block = nil, nil
+		case block != nil:
+			*block = append(*block, line)
+		}
+	}
+
+	return failures
+}
+
+// printSideBySide writes the got and want output of a failure in two columns, marking lines that differ.
+func printSideBySide(w io.Writer, failure exampleFailure) {
+	width := len("got")
+	for _, line := range failure.Got {
+		width = max(width, len(line))
+	}
+
+	fmt.Fprintf(w, "\n%s\n", failure.Name)
+	fmt.Fprintf(w, "  %-*s | %s\n", width, "got", "want")
+	fmt.Fprintf(w, "  %s-+-%s\n", strings.Repeat("-", width), strings.Repeat("-", width))
+
+	for i := 0; i < max(len(failure.Got), len(failure.Want)); i++ {
+		var got, want string
+		if i < len(failure.Got) {
+			got = failure.Got[i]
+		}
+
+		if i < len(failure.Want) {
+			want = failure.Want[i]
+		}
+
+		marker := " "
+		if got != want {
+			marker = "!"
+		}
+
+		fmt.Fprintf(w, "%s %-*s | %s\n", marker, width, got, want)
+	}
+}
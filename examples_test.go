@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_findExamples(t *testing.T) {
+	path, err := filepath.Abs("testdata/example_funcs.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, file := loadTestFile(path)
+	if pkg == nil {
+		t.Fatal("expected package to load")
+	}
+
+	expected := []Test{
+		{Name: "ExampleGreeter_Hello", IsExample: true, File: path, FilePath: path, LineNumber: 9, ExpectedOutput: "hello\n"},
+		{Name: "Example_unordered", IsExample: true, File: path, FilePath: path, LineNumber: 15, ExpectedOutput: "b\na\n"},
+	}
+
+	assert.Equal(t, expected, pkg.findExamples(file, path))
+}
+
+func Test_parseExampleFailures(t *testing.T) {
+	out := `=== RUN   ExampleHello
+--- FAIL: ExampleHello (0.00s)
+got:
+hello
+there
+want:
+hello
+world
+=== RUN   ExamplePass
+--- PASS: ExamplePass (0.00s)
+FAIL
+FAIL	example.com/pkg	0.002s
+`
+
+	expected := []exampleFailure{
+		{Name: "ExampleHello", Got: []string{"hello", "there"}, Want: []string{"hello", "world"}},
+	}
+
+	assert.Equal(t, expected, parseExampleFailures(strings.NewReader(out)))
+}
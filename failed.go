@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// resultsBucket stores the per test results of the last run in each module, keyed by module root.
+const resultsBucket = "results"
+
+// runResults are the results of every test of a run, by package and then test. The result of the package itself
+// is under "".
+type runResults struct {
+	Timestamp time.Time
+	Packages  map[string]map[string]string
+}
+
+// storeRunResults records the results of a run in the module at dir, replacing those of the previous run.
+func storeRunResults(dir string, results map[string]map[string]string) {
+	if len(results) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(runResults{Timestamp: time.Now(), Packages: results})
+	if err != nil {
+		panic(err)
+	}
+
+	db := getHistoryFile(historyFile)
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(resultsBucket))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(dir), data)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// loadRunResults returns the results of the last run in the module at dir.
+func loadRunResults(dir string) (runResults, error) {
+	db := getHistoryFile(historyFile)
+	defer db.Close()
+
+	var results runResults
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(resultsBucket))
+		if b == nil {
+			return nil
+		}
+
+		data := b.Get([]byte(dir))
+		if data == nil {
+			return nil
+		}
+
+		return json.Unmarshal(data, &results)
+	})
+	if err != nil {
+		return runResults{}, fmt.Errorf("error loading results: %w", err)
+	}
+
+	return results, nil
+}
+
+// failedCommands returns a go test command for each package with failures that runs only the failed tests. Parents
+// that failed because of a subtest aren't listed themselves since running the subtest runs them too. Packages that
+// failed without a failed test, IE they didn't build, are run in full.
+func failedCommands(modRoot string, results runResults) []exec.Cmd {
+	p, err := exec.LookPath("go")
+	if err != nil {
+		panic(err)
+	}
+
+	var cmds []exec.Cmd
+	for _, pkg := range slices.Sorted(maps.Keys(results.Packages)) {
+		tests := results.Packages[pkg]
+
+		var failed []Test
+		for _, name := range slices.Sorted(maps.Keys(tests)) {
+			if name == "" || tests[name] != "fail" {
+				continue
+			}
+
+			hasFailedSubtest := false
+			for other, result := range tests {
+				if result == "fail" && strings.HasPrefix(other, name+"/") {
+					hasFailedSubtest = true
+					break
+				}
+			}
+
+			if !hasFailedSubtest {
+				failed = append(failed, Test{Name: name})
+			}
+		}
+
+		if len(failed) == 0 {
+			if tests[""] != "fail" {
+				continue
+			}
+
+			failed = append(failed, Test{})
+		}
+
+		cmds = append(cmds, exec.Cmd{
+			Path: p,
+			Args: append([]string{"go"}, append(testArgs(pkg, modRoot, failed), "-json")...),
+			Dir:  modRoot,
+		})
+	}
+
+	return cmds
+}
+
+// rerunFailed runs the tests that failed in the last run in the module of dir. When attempts is more than zero the
+// tests still failing are rerun until they pass or they've been rerun that many more times.
+func rerunFailed(dir string, attempts int) error {
+	modRoot := lookupModuleRoot(dir)
+	if modRoot == "" {
+		return errors.New("no module found")
+	}
+
+	for attempt := 0; ; attempt++ {
+		results, err := loadRunResults(modRoot)
+		if err != nil {
+			return err
+		}
+
+		cmds := failedCommands(modRoot, results)
+		if len(cmds) == 0 {
+			fmt.Println("No failed tests in the last run")
+			return nil
+		}
+
+		outcome := runBatch(cmds)
+		logBatchHistory(cmds, outcome)
+
+		if outcome.Pass || attempt >= attempts {
+			if !outcome.Pass && attempts > 0 {
+				fmt.Printf("Tests still failing after %d attempts\n", attempt+1)
+			}
+
+			return nil
+		}
+
+		fmt.Printf("\nRerunning failed tests, attempt %d of %d\n", attempt+2, attempts+1)
+	}
+}
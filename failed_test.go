@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_failedCommands(t *testing.T) {
+	quiet = boolPtr(false)
+
+	results := runResults{Packages: map[string]map[string]string{
+		"example.com/m/a": {
+			"":            "fail",
+			"TestA":       "fail",
+			"TestA/one":   "fail",
+			"TestA/two":   "pass",
+			"TestB":       "pass",
+			"TestC":       "fail",
+			"TestC/inner": "skip",
+		},
+		"example.com/m/passing": {
+			"":      "pass",
+			"TestD": "pass",
+		},
+		"example.com/m/broken": {
+			"": "fail",
+		},
+	}}
+
+	var args [][]string
+	for _, cmd := range failedCommands("/mod", results) {
+		assert.Equal(t, "/mod", cmd.Dir)
+		args = append(args, cmd.Args)
+	}
+
+	assert.Equal(t, [][]string{
+		{"go", "test", "-v", "example.com/m/a", "-run", "^TestA$/^one$|^TestC$", "-json"},
+		{"go", "test", "-v", "example.com/m/broken", "-json"},
+	}, args)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// flakyBucket stores the reports of every -flaky run of a test so flakiness can be compared over time.
+const flakyBucket = "flaky"
+
+// flakyTrendSize is how many previous reports are shown after a -flaky run.
+const flakyTrendSize = 5
+
+// FlakyReport summarizes repeated runs of a single test.
+type FlakyReport struct {
+	Timestamp time.Time
+	Test      string
+	Runs      int
+	Passed    int
+	// Failures counts the failures by signature, Messages holds an example of the full message for each.
+	Failures map[string]int
+	Messages map[string]string
+	// Seeds are the -shuffle seeds of the failing runs by signature, passing one to -shuffle reproduces the order.
+	Seeds        map[string][]string
+	MinElapsed   float64
+	MaxElapsed   float64
+	TotalElapsed float64
+}
+
+// PassRate is the percentage of runs that passed.
+func (r FlakyReport) PassRate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+
+	return float64(r.Passed) / float64(r.Runs) * 100
+}
+
+// flakyRun is the outcome of a single run of the test.
+type flakyRun struct {
+	Pass    bool
+	Elapsed float64
+	Seed    string
+	Message string
+}
+
+// runFlaky runs t n times, each in a new go test invocation with a shuffled test order, and reports how often it
+// failed and why. The report is stored with the previous ones for the test.
+func runFlaky(t Test, n int) {
+	path, modRoot := testToPathAndRoot(t)
+
+	p, err := exec.LookPath("go")
+	if err != nil {
+		panic(err)
+	}
+
+	// -count=1 so results aren't cached and every run actually runs, the extra arguments can still override them,
+	// IE -shuffle with the seed of a failing run
+	args := append([]string{"go"}, testArgs(path, modRoot, []Test{t}, "-count=1", "-shuffle=on", "-json")...)
+
+	report := FlakyReport{
+		Timestamp: time.Now(),
+		Test:      t.Name,
+		Failures:  map[string]int{},
+		Messages:  map[string]string{},
+		Seeds:     map[string][]string{},
+	}
+
+	fmt.Println("Running", args, "@", modRoot, n, "times")
+
+	var cmd exec.Cmd
+	for i := 1; i <= n; i++ {
+		var stderr bytes.Buffer
+		events := newTestEventWriter(io.Discard)
+		cmd = exec.Cmd{
+			Path:   p,
+			Env:    os.Environ(),
+			Args:   args,
+			Dir:    modRoot,
+			Stdout: events,
+			Stderr: &stderr,
+		}
+
+		// a run that hangs is dumped and stopped like any other run rather than blocking the rest
+		err := runCommand(&cmd, events)
+		var exit *exec.ExitError
+		if err != nil && !errors.As(err, &exit) {
+			panic(err)
+		}
+
+		events.Flush()
+		hangDump := reportHang(events)
+
+		run := flakyOutcome(events, t.Name, stderr.String(), err)
+		report.add(run)
+
+		status := resultColor("pass", "PASS")
+		if !run.Pass {
+			status = resultColor("fail", "FAIL") + " " + failureSignature(run.Message)
+		}
+
+		fmt.Printf("Run %d/%d: %s (%.2fs, seed %s)\n", i, n, status, run.Elapsed, run.Seed)
+		if hangDump != "" {
+			fmt.Println("Hung, goroutine dump saved to:", hangDump)
+		}
+	}
+
+	previous := storeFlakyReport(flakyKey(modRoot, path, t.Name), report)
+	printFlakyReport(report, previous)
+
+	logRunHistory(cmd, runOutcome{Pass: report.Passed == report.Runs})
+}
+
+// flakyOutcome returns the outcome of the test named name from a single run, err is the error the run exited
+// with.
+func flakyOutcome(events *testEventWriter, name, stderr string, err error) flakyRun {
+	// a run that failed before reporting any result, IE the test binary crashed or didn't build, failed too
+	run := flakyRun{Pass: len(events.results) > 0 || err == nil, Seed: events.shuffleSeed}
+
+	for pkg, results := range events.results {
+		// tests matched by a wildcard pattern or packages that failed to build only have the package result
+		result, ok := results[name]
+		key := pkg + " " + name
+		if !ok {
+			result = results[""]
+			key = pkg + " "
+		}
+
+		run.Pass = run.Pass && result != "fail"
+		run.Elapsed += events.elapsed[key]
+	}
+
+	if run.Pass {
+		return run
+	}
+
+	var message strings.Builder
+	for _, failed := range events.failedLeaves() {
+		for _, output := range failed.Output {
+			message.WriteString(output)
+		}
+	}
+
+	// nothing was logged, IE the package didn't build
+	if message.Len() == 0 {
+		message.WriteString(stderr)
+	}
+
+	run.Message = message.String()
+
+	return run
+}
+
+func (r *FlakyReport) add(run flakyRun) {
+	r.Runs++
+	r.TotalElapsed += run.Elapsed
+	if r.Runs == 1 || run.Elapsed < r.MinElapsed {
+		r.MinElapsed = run.Elapsed
+	}
+
+	r.MaxElapsed = max(r.MaxElapsed, run.Elapsed)
+
+	if run.Pass {
+		r.Passed++
+		return
+	}
+
+	signature := failureSignature(run.Message)
+	r.Failures[signature]++
+	if _, ok := r.Messages[signature]; !ok {
+		r.Messages[signature] = run.Message
+	}
+
+	if run.Seed != "" {
+		r.Seeds[signature] = append(r.Seeds[signature], run.Seed)
+	}
+}
+
+var (
+	hexPattern    = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	numberPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+)
+
+// failureSignature reduces a failure message to its first line with numbers and addresses masked, so failures
+// that only differ by values, durations or goroutine ids are grouped together.
+func failureSignature(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// keep the line number of file:line prefixes, it's what tells failures apart
+		prefix := ""
+		if file, rest, ok := strings.Cut(line, ": "); ok && strings.Contains(file, ".go:") {
+			prefix, line = file+": ", rest
+		}
+
+		line = hexPattern.ReplaceAllString(line, "ADDR")
+		return prefix + numberPattern.ReplaceAllString(line, "N")
+	}
+
+	return "no output"
+}
+
+func flakyKey(modRoot, path, name string) []byte {
+	return []byte(modRoot + " " + path + " " + name)
+}
+
+// storeFlakyReport adds the report to those of the test and returns the previous ones, newest first.
+func storeFlakyReport(key []byte, report FlakyReport) []FlakyReport {
+	db := getHistoryFile(historyFile)
+	defer db.Close()
+
+	var reports []FlakyReport
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(flakyBucket))
+		if err != nil {
+			return err
+		}
+
+		if data := b.Get(key); data != nil {
+			if err := json.Unmarshal(data, &reports); err != nil && *verbose {
+				fmt.Println("Ignoring invalid flaky reports", err)
+			}
+		}
+
+		data, err := json.Marshal(append(reports, report))
+		if err != nil {
+			return err
+		}
+
+		return b.Put(key, data)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	slices.Reverse(reports)
+
+	return reports
+}
+
+func printFlakyReport(report FlakyReport, previous []FlakyReport) {
+	fmt.Println()
+	fmt.Printf("%s: %d/%d passed (%.1f%%)\n", report.Test, report.Passed, report.Runs, report.PassRate())
+	fmt.Printf("Duration: min %.2fs, avg %.2fs, max %.2fs\n", report.MinElapsed,
+		report.TotalElapsed/float64(max(report.Runs, 1)), report.MaxElapsed)
+
+	// most common failures first
+	signatures := slices.Collect(maps.Keys(report.Failures))
+	slices.SortFunc(signatures, func(a, b string) int {
+		if report.Failures[a] != report.Failures[b] {
+			return report.Failures[b] - report.Failures[a]
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	for _, signature := range signatures {
+		fmt.Println()
+		fmt.Println(resultColor("fail", fmt.Sprintf("%dx %s", report.Failures[signature], signature)))
+		if seeds := report.Seeds[signature]; len(seeds) > 0 {
+			fmt.Println("Seeds:", strings.Join(seeds, ", "))
+		}
+
+		fmt.Print(report.Messages[signature])
+	}
+
+	if len(previous) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Previous runs:")
+	for _, prev := range previous[:min(len(previous), flakyTrendSize)] {
+		fmt.Printf("%s - %d/%d passed (%.1f%%)\n", prev.Timestamp.Format("01/02/2006 @ 15:04:05"), prev.Passed,
+			prev.Runs, prev.PassRate())
+	}
+}
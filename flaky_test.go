@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_failureSignature(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "values are masked",
+			message:  "    a_test.go:12: expected 3 got 7\n    a_test.go:13: more\n",
+			expected: "a_test.go:12: expected N got N",
+		},
+		{
+			name:     "addresses are masked",
+			message:  "\npanic: runtime error: invalid memory address 0xc000012345 [recovered]\n",
+			expected: "panic: runtime error: invalid memory address ADDR [recovered]",
+		},
+		{
+			name:     "durations are masked",
+			message:  "    a_test.go:30: timed out after 1.5s\n",
+			expected: "a_test.go:30: timed out after Ns",
+		},
+		{
+			name:     "no output",
+			message:  "\n",
+			expected: "no output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, failureSignature(tt.message))
+		})
+	}
+}
+
+func Test_flakyOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   string
+		stderr   string
+		err      error
+		expected flakyRun
+	}{
+		{
+			name:     "passed",
+			stream:   `{"Action":"pass","Package":"example.com/pkg","Test":"TestA","Elapsed":0.5}` + "\n",
+			expected: flakyRun{Pass: true, Elapsed: 0.5},
+		},
+		{
+			name: "failed",
+			stream: `{"Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"    a_test.go:9: got 1\n"}` + "\n" +
+				`{"Action":"fail","Package":"example.com/pkg","Test":"TestA","Elapsed":0.5}` + "\n",
+			err:      errors.New("exit status 1"),
+			expected: flakyRun{Elapsed: 0.5, Message: "    a_test.go:9: got 1\n"},
+		},
+		{
+			name:     "exited without any result",
+			stderr:   "signal: killed\n",
+			err:      errors.New("signal: killed"),
+			expected: flakyRun{Message: "signal: killed\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := newTestEventWriter(io.Discard)
+			events.Write([]byte(tt.stream))
+			events.Flush()
+
+			assert.Equal(t, tt.expected, flakyOutcome(events, "TestA", tt.stderr, tt.err))
+		})
+	}
+}
+
+func Test_FlakyReport_add(t *testing.T) {
+	report := FlakyReport{Failures: map[string]int{}, Messages: map[string]string{}, Seeds: map[string][]string{}}
+	report.add(flakyRun{Pass: true, Elapsed: 0.5})
+	report.add(flakyRun{Elapsed: 0.2, Seed: "1", Message: "    a_test.go:9: got 1\n"})
+	report.add(flakyRun{Elapsed: 0.9, Seed: "2", Message: "    a_test.go:9: got 2\n"})
+	report.add(flakyRun{Pass: true, Elapsed: 0.4})
+
+	assert.Equal(t, 4, report.Runs)
+	assert.Equal(t, 2, report.Passed)
+	assert.Equal(t, 50.0, report.PassRate())
+	assert.Equal(t, 0.2, report.MinElapsed)
+	assert.Equal(t, 0.9, report.MaxElapsed)
+	assert.Equal(t, map[string]int{"a_test.go:9: got N": 2}, report.Failures)
+	assert.Equal(t, map[string][]string{"a_test.go:9: got N": {"1", "2"}}, report.Seeds)
+	assert.Equal(t, "    a_test.go:9: got 1\n", report.Messages["a_test.go:9: got N"])
+}
+
+func Test_storeFlakyReport(t *testing.T) {
+	isolateHistory(t)
+
+	key := flakyKey("/mod", "./pkg", "TestA")
+	assert.Empty(t, storeFlakyReport(key, FlakyReport{Test: "TestA", Runs: 1}))
+	assert.Empty(t, storeFlakyReport(flakyKey("/mod", "./pkg", "TestB"), FlakyReport{Test: "TestB", Runs: 2}))
+
+	previous := storeFlakyReport(key, FlakyReport{Test: "TestA", Runs: 3})
+	assert.Len(t, previous, 1)
+	assert.Equal(t, 1, previous[0].Runs)
+
+	previous = storeFlakyReport(key, FlakyReport{Test: "TestA", Runs: 4})
+	assert.Equal(t, []int{3, 1}, []int{previous[0].Runs, previous[1].Runs})
+}
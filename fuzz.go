@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// findFuzzTests returns all fuzz tests in the file along with their f.Add seeds and testdata/fuzz corpus entries.
+func (tp *testPackage) findFuzzTests(f *ast.File, path string) []Test {
+	var tests []Test
+
+	for _, decl := range f.Decls {
+		x, ok := decl.(*ast.FuncDecl)
+		if !ok || x.Recv != nil || x.Body == nil || !strings.HasPrefix(x.Name.Name, "Fuzz") {
+			continue
+		}
+
+		if *verbose {
+			fmt.Println("Evalutating", x.Name.Name)
+		}
+
+		// create entry to fuzz the whole target
+		tests = append(tests, Test{
+			File:       path,
+			Name:       x.Name.Name,
+			IsFuzz:     true,
+			FilePath:   path,
+			LineNumber: tp.Fset.Position(x.Pos()).Line,
+		})
+
+		// seeds are named in the order they're added, IE FuzzX/seed#0
+		var seed int
+		ast.Inspect(x.Body, func(n ast.Node) bool {
+			c, ok := n.(*ast.CallExpr)
+			if !ok || !tp.isFuzzAddCall(c) {
+				return true
+			}
+
+			tests = append(tests, Test{
+				File:       path,
+				Name:       fmt.Sprintf("%s/seed#%d", x.Name.Name, seed),
+				IsFuzz:     true,
+				FilePath:   path,
+				LineNumber: tp.Fset.Position(c.Pos()).Line,
+			})
+			seed++
+
+			return true
+		})
+
+		tests = append(tests, findFuzzCorpus(path, x.Name.Name)...)
+	}
+
+	return tests
+}
+
+// isFuzzAddCall reports if c is a call to Add on a *testing.F.
+func (tp *testPackage) isFuzzAddCall(c *ast.CallExpr) bool {
+	sel, ok := c.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Add" {
+		return false
+	}
+
+	fn, ok := tp.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "testing" {
+		return false
+	}
+
+	recv := fn.Type().(*types.Signature).Recv()
+
+	return recv != nil && strings.HasSuffix(recv.Type().String(), "testing.F")
+}
+
+// findFuzzCorpus returns the entries in testdata/fuzz/<name> next to the test file. go test names these by file name.
+func findFuzzCorpus(path, name string) []Test {
+	entries, err := os.ReadDir(filepath.Join(filepath.Dir(path), "testdata", "fuzz", name))
+	if err != nil {
+		return nil
+	}
+
+	var tests []Test
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		tests = append(tests, Test{
+			File:       path,
+			Name:       name + "/" + entry.Name(),
+			IsFuzz:     true,
+			FilePath:   filepath.Join(filepath.Dir(path), "testdata", "fuzz", name, entry.Name()),
+			LineNumber: 1,
+		})
+	}
+
+	return tests
+}
+
+// failingInput matches the line go test prints when fuzzing finds a failure, IE
+// Failing input written to testdata/fuzz/FuzzX/582528ddfad69eb5
+var failingInput = regexp.MustCompile(`Failing input written to testdata/fuzz/(\S+)/(\S+)`)
+
+// runFuzz fuzzes the selected target, or replays a single seed or corpus entry when one is selected.
+func runFuzz(t Test) {
+	path, modRoot := testToPathAndRoot(t)
+
+	p, err := exec.LookPath("go")
+	if err != nil {
+		panic(err)
+	}
+
+	// capture the output so newly found failing inputs can be added to the history
+	fuzzBuffer := &bytes.Buffer{}
+
+	cmd := exec.Cmd{
+		Path:   p,
+		Env:    os.Environ(),
+		Args:   append([]string{"go"}, fuzzArgs(path, modRoot, t.Name)...),
+		Dir:    modRoot,
+		Stdout: io.MultiWriter(os.Stdout, fuzzBuffer),
+		Stderr: os.Stderr,
+	}
+
+	fmt.Println("Running", cmd.Args, "@", cmd.Dir)
+
+	var pass bool
+	err = cmd.Run()
+	var exit *exec.ExitError
+	switch {
+	case err == nil:
+		pass = true
+	case errors.As(err, &exit):
+	// do nothing
+	default:
+		panic(err)
+	}
+
+	logRunHistory(cmd, runOutcome{Pass: pass})
+
+	// log a replay of each failing input so it can be picked from history or re-run with -r
+	for _, match := range failingInput.FindAllStringSubmatch(fuzzBuffer.String(), -1) {
+		replay := exec.Cmd{
+			Path: p,
+			Args: append([]string{"go"}, fuzzArgs(path, modRoot, match[1]+"/"+match[2])...),
+			Dir:  modRoot,
+		}
+
+		fmt.Println("Failing input saved to history, replay with: gotest -r")
+		logRunHistory(replay, runOutcome{})
+	}
+}
+
+// fuzzArgs returns the go test arguments to fuzz the target name, or to replay it when it's a seed or corpus entry.
+func fuzzArgs(path, modRoot, name string) []string {
+	args := []string{"test", quietMode(), path, "-run", runPattern(name)}
+	args = append(args, buildFlags()...)
+
+	// only fuzz when the target itself is selected, seeds and corpus entries are just replayed
+	if !strings.Contains(name, "/") {
+		args = append(args, "-fuzz", runPattern(name))
+		if globalConfig.FuzzTime != "" {
+			args = append(args, "-fuzztime", globalConfig.FuzzTime)
+		}
+
+		if globalConfig.FuzzMinimizeTime != "" {
+			args = append(args, "-fuzzminimizetime", globalConfig.FuzzMinimizeTime)
+		}
+	}
+
+	return append(args, extraArgs(modRoot)...)
+}
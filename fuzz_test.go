@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_fuzzArgs(t *testing.T) {
+	config := *globalConfig
+	t.Cleanup(func() {
+		*globalConfig = config
+		passThroughArgs = nil
+	})
+
+	quiet = boolPtr(false)
+	globalConfig.BuildTags = "integration"
+	globalConfig.FuzzTime = "10s"
+	globalConfig.FuzzMinimizeTime = ""
+	passThroughArgs = []string{"-count=1"}
+
+	tests := []struct {
+		name     string
+		target   string
+		expected []string
+	}{
+		{
+			name:     "target",
+			target:   "FuzzX",
+			expected: []string{"test", "-v", "./pkg", "-run", "^FuzzX$", "-tags=integration", "-fuzz", "^FuzzX$", "-fuzztime", "10s", "-count=1"},
+		},
+		{
+			name:     "replayed corpus entry",
+			target:   "FuzzX/582528ddfad69eb5",
+			expected: []string{"test", "-v", "./pkg", "-run", "^FuzzX$/^582528ddfad69eb5$", "-tags=integration", "-count=1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, fuzzArgs("./pkg", "/mod", tt.target))
+		})
+	}
+}
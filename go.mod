@@ -1,17 +1,20 @@
 module github.com/MordFustang21/gotest
 
-go 1.21
+go 1.25.0
 
 require (
+	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e
 	github.com/manifoldco/promptui v0.9.0
 	github.com/stretchr/testify v1.9.0
 	go.etcd.io/bbolt v1.3.9
+	golang.org/x/sys v0.43.0
+	golang.org/x/tools v0.44.0
 )
 
 require (
-	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/mod v0.35.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
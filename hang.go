@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// dumpDir is the directory in the home directory goroutine dumps of hung runs are saved to, the history entry of
+// the run has the path of its dump.
+const dumpDir = ".go-test-dumps"
+
+// stopRun stops the tests run by runCommand once it's closed, IE when an editor cancels a run started through serve.
+var stopRun chan struct{}
+
+// hangTimeout returns how long the tests can go without any output before they're considered hung, zero when
+// hangs aren't watched for.
+func hangTimeout() time.Duration {
+	if *hangAfter > 0 || globalConfig.HangTimeout == "" {
+		return *hangAfter
+	}
+
+	timeout, err := time.ParseDuration(globalConfig.HangTimeout)
+	if err != nil {
+		panic(fmt.Errorf("invalid HangTimeout %q: %w", globalConfig.HangTimeout, err))
+	}
+
+	return timeout
+}
+
+// runCommand runs cmd like cmd.Run. When a hang timeout is set and the tests write nothing for that long they're
+// sent SIGQUIT, which makes the test binary dump every goroutine's stack and exit. The dump is collected by events
+// instead of being printed and reportHang summarizes it after the run. The tests are killed when stopRun is closed.
+func runCommand(cmd *exec.Cmd, events *testEventWriter) error {
+	timeout := hangTimeout()
+	stop := stopRun
+	if timeout <= 0 && stop == nil {
+		return cmd.Run()
+	}
+
+	activity := make(chan struct{}, 1)
+	cmd.Stdout = activityWriter{w: cmd.Stdout, activity: activity}
+	cmd.Stderr = activityWriter{w: cmd.Stderr, activity: activity}
+
+	// the signal has to reach the test binary, go test runs it as a child process
+	setProcessGroup(cmd)
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	// the tests are no longer in the terminal's process group so interrupts have to be passed on
+	interrupts := notifyInterrupt()
+	defer stopInterrupt(interrupts)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	// without a hang timeout the command is only watched for being stopped
+	var timer *time.Timer
+	var hung <-chan time.Time
+	if timeout > 0 {
+		timer = time.NewTimer(timeout)
+		defer timer.Stop()
+
+		hung = timer.C
+	}
+
+	quitSent := false
+	for {
+		select {
+		case err := <-done:
+			return err
+
+		case <-activity:
+			if !quitSent && timer != nil {
+				timer.Reset(timeout)
+			}
+
+		case <-interrupts:
+			interruptProcessGroup(cmd)
+
+		case <-stop:
+			killProcessGroup(cmd)
+			stop = nil
+
+		case <-hung:
+			// the tests didn't exit after dumping their goroutines, IE they handle SIGQUIT themselves
+			if quitSent {
+				killProcessGroup(cmd)
+				continue
+			}
+
+			fmt.Printf("No test output for %s, sending SIGQUIT to capture the goroutines\n", timeout)
+			events.captureDump()
+			quitProcessGroup(cmd)
+
+			quitSent = true
+			timer.Reset(timeout)
+		}
+	}
+}
+
+// activityWriter signals every write to activity.
+type activityWriter struct {
+	w        io.Writer
+	activity chan struct{}
+}
+
+func (a activityWriter) Write(p []byte) (int, error) {
+	select {
+	case a.activity <- struct{}{}:
+	default:
+	}
+
+	return a.w.Write(p)
+}
+
+// goroutineGroup is a set of goroutines with identical stacks in the same state.
+type goroutineGroup struct {
+	State  string
+	Frames []string
+	IDs    []string
+	// InPackage is set when the goroutines are blocked in the package being tested.
+	InPackage bool
+}
+
+var goroutineHeader = regexp.MustCompile(`^goroutine (\d+) .*?\[([^\]]*)\]:$`)
+
+// groupGoroutines parses a goroutine dump and groups goroutines with the same state and stack, the groups blocked
+// in one of the packages come first and then the largest groups. Calls within the runtime are left out of the
+// stacks, goroutines that only run the runtime, IE the garbage collector, are counted as hidden.
+func groupGoroutines(dump []string, packages []string) ([]goroutineGroup, int) {
+	var groups []goroutineGroup
+	var hidden int
+	var id, state string
+	var frames []string
+
+	flush := func() {
+		if id == "" {
+			return
+		}
+
+		frames = slices.DeleteFunc(frames, func(frame string) bool {
+			call := frameCall(frame)
+			return strings.HasPrefix(call, "runtime.") || strings.HasPrefix(call, "internal/runtime/")
+		})
+
+		// goroutine 0 is the stack the runtime itself runs on
+		if len(frames) == 0 || id == "0" {
+			hidden++
+			id, state = "", ""
+			return
+		}
+
+		i := slices.IndexFunc(groups, func(g goroutineGroup) bool {
+			return g.State == state && slices.Equal(g.Frames, frames)
+		})
+		if i < 0 {
+			groups = append(groups, goroutineGroup{State: state, Frames: frames, InPackage: inPackages(frames, packages)})
+			i = len(groups) - 1
+		}
+
+		groups[i].IDs = append(groups[i].IDs, id)
+		id, state, frames = "", "", nil
+	}
+
+	for _, line := range strings.Split(strings.Join(dump, ""), "\n") {
+		if match := goroutineHeader.FindStringSubmatch(line); match != nil {
+			flush()
+
+			// the wait duration would keep otherwise identical goroutines apart, IE "chan receive, 2 minutes"
+			id = match[1]
+			state, _, _ = strings.Cut(match[2], ",")
+			continue
+		}
+
+		if id == "" {
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+
+		// the file of a call is on the line after its function, IE "\t/tmp/e2e/a_test.go:9 +0x1d"
+		case strings.HasPrefix(line, "\t") && len(frames) > 0:
+			location, _, _ := strings.Cut(strings.TrimSpace(line), " +0x")
+			frames[len(frames)-1] = location + " " + frames[len(frames)-1]
+
+		default:
+			frames = append(frames, callName(line))
+		}
+	}
+
+	flush()
+
+	slices.SortStableFunc(groups, func(a, b goroutineGroup) int {
+		if a.InPackage != b.InPackage {
+			if a.InPackage {
+				return -1
+			}
+
+			return 1
+		}
+
+		return len(b.IDs) - len(a.IDs)
+	})
+
+	return groups, hidden
+}
+
+// callName returns the function of a line of a stack without its arguments, IE "testing.(*T).Run" for
+// "testing.(*T).Run(0xc000003a40, {0x5b8f2e, 0x5})". Creation sites keep the function but not the goroutine.
+func callName(line string) string {
+	if name, ok := strings.CutPrefix(line, "created by "); ok {
+		name, _, _ = strings.Cut(name, " in goroutine ")
+		return "created by " + name
+	}
+
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// frameCall returns the function called by a frame of a group, IE "sync.(*Mutex).Lock" for
+// "/usr/local/go/src/sync/mutex.go:46 sync.(*Mutex).Lock".
+func frameCall(frame string) string {
+	_, call, _ := strings.Cut(frame, " ")
+	return strings.TrimPrefix(call, "created by ")
+}
+
+// inPackages reports if any of the frames is a call to a function of one of the packages, or their external tests.
+func inPackages(frames, packages []string) bool {
+	for _, frame := range frames {
+		call := frameCall(frame)
+		for _, pkg := range packages {
+			if strings.HasPrefix(call, pkg+".") || strings.HasPrefix(call, pkg+"_test.") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// reportHang prints the goroutines captured when the run hung grouped by stack and saves the full dump. It returns
+// the path of the dump, empty when the run didn't hang.
+func reportHang(events *testEventWriter) string {
+	if len(events.dump) == 0 {
+		return ""
+	}
+
+	// the package that was running when the tests hung hasn't reported a result yet, but its tests have run
+	var packages []string
+	for pkg := range events.seen {
+		packages = append(packages, pkg)
+	}
+
+	groups, hidden := groupGoroutines(events.dump, packages)
+
+	fmt.Fprintln(events.out)
+	fmt.Fprintln(events.out, "Goroutines when the tests hung:")
+	for _, group := range groups {
+		header := fmt.Sprintf("%d goroutines [%s]: %s", len(group.IDs), group.State, strings.Join(group.IDs, ", "))
+		if len(group.IDs) == 1 {
+			header = fmt.Sprintf("goroutine %s [%s]", group.IDs[0], group.State)
+		}
+
+		if group.InPackage {
+			header = resultColor("fail", header)
+		}
+
+		fmt.Fprintln(events.out)
+		fmt.Fprintln(events.out, header)
+		for _, frame := range group.Frames {
+			fmt.Fprintln(events.out, "    "+frame)
+		}
+	}
+
+	path, err := saveHangDump(events.dump)
+	if err != nil {
+		fmt.Fprintln(events.out, "Error saving goroutine dump:", err)
+		return ""
+	}
+
+	fmt.Fprintln(events.out)
+	if hidden > 0 {
+		fmt.Fprintf(events.out, "%d runtime goroutines aren't shown\n", hidden)
+	}
+
+	fmt.Fprintln(events.out, "Goroutine dump saved to:", path)
+
+	return path
+}
+
+func saveHangDump(dump []string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(home, dumpDir)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(dir, time.Now().Format("20060102-150405")+"-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(strings.Join(dump, ""))
+	if err != nil {
+		return "", err
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,29 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing, without SIGQUIT the tests can't be made to dump their goroutines so a hang just
+// stops them.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func quitProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+func interruptProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+// notifyInterrupt returns nil since the tests get interrupts from the console themselves.
+func notifyInterrupt() chan os.Signal {
+	return nil
+}
+
+func stopInterrupt(interrupts chan os.Signal) {}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_groupGoroutines(t *testing.T) {
+	dump := `SIGQUIT: quit
+PC=0x48e4a1 m=0 sigcode=0
+
+goroutine 0 gp=0x6fc6a0 m=0 mp=0x6fd6a0 [idle]:
+runtime.futex(0x6fd7f8, 0x80, 0x0, 0x0, 0x0, 0x0)
+	/usr/local/go/src/runtime/sys_linux_amd64.s:575 +0x21 fp=0x7ffcb1f990e0 sp=0x7ffcb1f990d8 pc=0x48e4a1
+
+goroutine 1 gp=0x1395f6f201e0 m=nil [chan receive, 2 minutes]:
+runtime.gopark(0x6d5d50?, 0x7f76bac53420?, 0x7e?, 0x0?, 0x6b6428?)
+	/usr/local/go/src/runtime/proc.go:474 +0xca fp=0x1395f6f7c908 sp=0x1395f6f7c8e8 pc=0x4864aa
+testing.(*T).Run(0x1395f6fba008, {0x555bcc?, 0x1395f6f7caa0?}, 0x6d6ef8)
+	/usr/local/go/src/testing/testing.go:2266 +0x4f2 fp=0x1395f6f7ca80 sp=0x1395f6f7c9a8 pc=0x4eec72
+
+goroutine 2 gp=0x1395f6f20b40 m=nil [force gc (idle)]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:474 +0xca
+created by runtime.init.7 in goroutine 1
+	/usr/local/go/src/runtime/proc.go:375 +0x1a
+
+goroutine 9 gp=0x1395f6f21a40 m=nil [sync.Mutex.Lock]:
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+example.com/e2e.TestHang.func1()
+	/tmp/e2e/hang_test.go:12 +0x25
+created by example.com/e2e.TestHang in goroutine 8
+	/tmp/e2e/hang_test.go:12 +0x5c
+
+goroutine 10 gp=0x1395f6f21c00 m=nil [sync.Mutex.Lock, 1 minutes]:
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+example.com/e2e.TestHang.func1()
+	/tmp/e2e/hang_test.go:12 +0x25
+created by example.com/e2e.TestHang in goroutine 8
+	/tmp/e2e/hang_test.go:12 +0x5c
+
+goroutine 11 gp=0x1395f6f21dc0 m=nil [chan receive]:
+testing.(*T).Run(0x1395f6fba008, {0x555bcc?, 0x1395f6f7caa0?}, 0x6d6ef8)
+	/usr/local/go/src/testing/testing.go:2266 +0x4f2
+
+rax    0xca
+rbx    0x0
+`
+
+	// the dump arrives as output events, one line at a time
+	lines := strings.SplitAfter(dump, "\n")
+	groups, hidden := groupGoroutines(lines, []string{"example.com/e2e"})
+
+	assert.Equal(t, 2, hidden)
+	assert.Equal(t, []goroutineGroup{
+		{
+			State: "sync.Mutex.Lock",
+			Frames: []string{
+				"/usr/local/go/src/sync/mutex.go:46 sync.(*Mutex).Lock",
+				"/tmp/e2e/hang_test.go:12 example.com/e2e.TestHang.func1",
+				"/tmp/e2e/hang_test.go:12 created by example.com/e2e.TestHang",
+			},
+			IDs:       []string{"9", "10"},
+			InPackage: true,
+		},
+		{
+			State:  "chan receive",
+			Frames: []string{"/usr/local/go/src/testing/testing.go:2266 testing.(*T).Run"},
+			IDs:    []string{"1", "11"},
+		},
+	}, groups)
+}
+
+func Test_callName(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{line: "testing.(*T).Run(0xc000003a40, {0x5b8f2e, 0x5}, 0x5c4b98)", expected: "testing.(*T).Run"},
+		{line: "sync.(*Mutex).Lock(...)", expected: "sync.(*Mutex).Lock"},
+		{line: "created by example.com/e2e.TestHang in goroutine 8", expected: "created by example.com/e2e.TestHang"},
+		{line: "...additional frames elided...", expected: "...additional frames elided..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			assert.Equal(t, tt.expected, callName(tt.line))
+		})
+	}
+}
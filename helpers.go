@@ -0,0 +1,102 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// helperScope maps the parameters of a helper function to the arguments it was called with, so a t.Run within the
+// helper can be named from the caller's arguments.
+type helperScope struct {
+	args   map[types.Object]ast.Expr
+	parent *helperScope
+	depth  int
+}
+
+// resolve replaces expr with the argument passed for it when expr is a parameter of the helper, following nested
+// helpers out to the test itself.
+func (s *helperScope) resolve(tp *testPackage, expr ast.Expr) ast.Expr {
+	for ; s != nil; s = s.parent {
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			return expr
+		}
+
+		// closures inside a helper can reference the parameters of any enclosing helper so keep looking
+		if arg, ok := s.args[tp.TypesInfo.ObjectOf(ident)]; ok {
+			expr = arg
+		}
+	}
+
+	return expr
+}
+
+// binds reports if expr is the argument passed for one of the helper's parameters.
+func (s *helperScope) binds(expr ast.Expr) bool {
+	for _, arg := range s.args {
+		if arg == expr {
+			return true
+		}
+	}
+
+	return false
+}
+
+// helperCall returns the body of the function called by c when it is declared in the package, either as a function
+// or a closure assigned to a variable, along with the scope mapping its parameters to the call's arguments.
+func (tp *testPackage) helperCall(c *ast.CallExpr, scope *helperScope) (*ast.BlockStmt, *helperScope) {
+	depth := 1
+	if scope != nil {
+		depth = scope.depth + 1
+	}
+
+	if depth > globalConfig.HelperDepth {
+		return nil, nil
+	}
+
+	// closures passed into a helper can be called by it directly, IE fn(t)
+	var fnType *ast.FuncType
+	var body *ast.BlockStmt
+	switch fn := scope.resolve(tp, c.Fun).(type) {
+	case *ast.FuncLit:
+		fnType, body = fn.Type, fn.Body
+	case *ast.Ident, *ast.SelectorExpr:
+		var ident *ast.Ident
+		if sel, ok := fn.(*ast.SelectorExpr); ok {
+			ident = sel.Sel
+		} else {
+			ident = fn.(*ast.Ident)
+		}
+
+		obj := tp.TypesInfo.ObjectOf(ident)
+		if decl, ok := tp.funcs[obj]; ok {
+			fnType, body = decl.Type, decl.Body
+		} else if lit, ok := tp.values[obj].(*ast.FuncLit); ok {
+			fnType, body = lit.Type, lit.Body
+		}
+	}
+
+	if body == nil {
+		return nil, nil
+	}
+
+	helper := &helperScope{args: map[types.Object]ast.Expr{}, parent: scope, depth: depth}
+
+	var i int
+	for _, field := range fnType.Params.List {
+		for _, name := range field.Names {
+			// variadic parameters can't be mapped to a single argument
+			if _, ok := field.Type.(*ast.Ellipsis); ok || i >= len(c.Args) {
+				break
+			}
+
+			if obj := tp.TypesInfo.ObjectOf(name); obj != nil {
+				helper.args[obj] = c.Args[i]
+			}
+
+			i++
+		}
+	}
+
+	return body, helper
+}
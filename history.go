@@ -20,21 +20,59 @@ import (
 
 const historyFile = ".go-test.db"
 
-// HistoryEntry is a single entry in the history file.
+// HistoryEntry is a single entry in the history file. Args include any extra go test flags given after -- or
+// configured, so they're used again when the entry is rerun.
 type HistoryEntry struct {
 	Timestamp     time.Time
 	Path          string
 	Args          []string
 	Dir           string
 	LastRunStatus bool
+	// Batch holds the commands of a run across several packages, one per package. Path, Args and Dir are those of
+	// the first command.
+	Batch []BatchCommand `json:",omitempty"`
+	// Races are the data races the run found.
+	Races []RaceReport `json:",omitempty"`
+	// HangDump is the path of the goroutine dump captured when the run hung.
+	HangDump string `json:",omitempty"`
+}
+
+// runOutcome is the result of a run that's logged with its history entry.
+type runOutcome struct {
+	Pass bool
+	// Races are the data races the run found.
+	Races []RaceReport
+	// HangDump is the path of the goroutine dump captured when the run hung.
+	HangDump string
+}
+
+// BatchCommand is one of the go test commands of a batch run.
+type BatchCommand struct {
+	Path string
+	Args []string
+	Dir  string
 }
 
 // String returns a string representation of the HistoryEntry.
 func (h HistoryEntry) String() string {
-	return fmt.Sprintf("%s - %s %s", h.Timestamp.Format("01/02/2006 @ 15:04:05"), strings.Join(h.Args, " "),
+	return fmt.Sprintf("%s - %s %s", h.Timestamp.Format("01/02/2006 @ 15:04:05"), h.command(),
 		statusToStr(h.LastRunStatus))
 }
 
+// command returns the arguments of every command of the entry.
+func (h HistoryEntry) command() string {
+	if len(h.Batch) == 0 {
+		return strings.Join(h.Args, " ")
+	}
+
+	commands := make([]string, 0, len(h.Batch))
+	for _, cmd := range h.Batch {
+		commands = append(commands, strings.Join(cmd.Args, " "))
+	}
+
+	return strings.Join(commands, " && ")
+}
+
 func statusToStr(b bool) string {
 	if b {
 		return "✅"
@@ -63,7 +101,7 @@ func (h *HistoryEntry) Load(data []byte) {
 
 // Hash returns a hash of the HistoryEntry.
 func (h HistoryEntry) Hash() string {
-	hash := md5.Sum([]byte(fmt.Sprintf("%s-%s-%s", h.Path, strings.Join(h.Args, " "), h.Dir)))
+	hash := md5.Sum([]byte(fmt.Sprintf("%s-%s-%s", h.Path, h.command(), h.Dir)))
 	key := hex.EncodeToString(hash[:])
 
 	return key
@@ -85,15 +123,40 @@ func getHistoryFile(file string) *bolt.DB {
 	return db
 }
 
-func logRunHistory(command exec.Cmd, pass bool) {
+func logRunHistory(command exec.Cmd, outcome runOutcome) {
 	he := HistoryEntry{
 		Path:          command.Path,
 		Args:          command.Args,
 		Dir:           command.Dir,
 		Timestamp:     time.Now(),
-		LastRunStatus: pass,
+		LastRunStatus: outcome.Pass,
+		Races:         outcome.Races,
+		HangDump:      outcome.HangDump,
 	}
 
+	saveHistoryEntry(he)
+}
+
+// logBatchHistory logs the commands of a batch run as a single entry so they're replayed together.
+func logBatchHistory(commands []exec.Cmd, outcome runOutcome) {
+	he := HistoryEntry{
+		Path:          commands[0].Path,
+		Args:          commands[0].Args,
+		Dir:           commands[0].Dir,
+		Timestamp:     time.Now(),
+		LastRunStatus: outcome.Pass,
+		Races:         outcome.Races,
+		HangDump:      outcome.HangDump,
+	}
+
+	for _, cmd := range commands {
+		he.Batch = append(he.Batch, BatchCommand{Path: cmd.Path, Args: cmd.Args, Dir: cmd.Dir})
+	}
+
+	saveHistoryEntry(he)
+}
+
+func saveHistoryEntry(he HistoryEntry) {
 	file := getHistoryFile(historyFile)
 	defer file.Close()
 
@@ -120,7 +183,8 @@ func logRunHistory(command exec.Cmd, pass bool) {
 	}
 }
 
-func selectHistory() (HistoryEntry, error) {
+// historyEntries returns every entry of the history, the most recent first.
+func historyEntries() ([]HistoryEntry, error) {
 	file := getHistoryFile(historyFile)
 	defer file.Close()
 
@@ -142,7 +206,7 @@ func selectHistory() (HistoryEntry, error) {
 		return nil
 	})
 	if err != nil {
-		return HistoryEntry{}, fmt.Errorf("error retrieving history %w", err)
+		return nil, fmt.Errorf("error retrieving history %w", err)
 	}
 
 	// sort the entries by timestamp
@@ -154,6 +218,15 @@ func selectHistory() (HistoryEntry, error) {
 		return -1
 	})
 
+	return entries, nil
+}
+
+func selectHistory() (HistoryEntry, error) {
+	entries, err := historyEntries()
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+
 	subtestPrompt := promptui.Select{
 		Label: "Run from history",
 		Items: entries,
@@ -184,9 +257,6 @@ func selectHistory() (HistoryEntry, error) {
 }
 
 func getLastCommand() (HistoryEntry, error) {
-	file := getHistoryFile(historyFile)
-	defer file.Close()
-
 	// lookup the current module root based on working directory so that
 	// we only run the last test in the current module and not the last global test.
 	wd, err := os.Getwd()
@@ -194,16 +264,24 @@ func getLastCommand() (HistoryEntry, error) {
 		return HistoryEntry{}, fmt.Errorf("error getting working directory %w", err)
 	}
 
-	modRoot := lookupModuleRoot(wd)
+	return lastCommandIn(wd)
+}
+
+// lastCommandIn returns the last run in the module containing dir.
+func lastCommandIn(dir string) (HistoryEntry, error) {
+	file := getHistoryFile(historyFile)
+	defer file.Close()
+
+	modRoot := lookupModuleRoot(dir)
 
 	var lastCommand HistoryEntry
-	err = file.View(func(tx *bolt.Tx) error {
+	err := file.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte("history"))
 		if b == nil {
 			return nil
 		}
 
-		err = b.ForEach(func(k, v []byte) error {
+		err := b.ForEach(func(k, v []byte) error {
 			var he HistoryEntry
 			he.Load(v)
 
@@ -235,17 +313,30 @@ func getLastCommand() (HistoryEntry, error) {
 	return lastCommand, nil
 }
 
-func runHistoryEntry(he HistoryEntry) {
+// runHistoryEntry reruns the commands of he and reports if they passed.
+func runHistoryEntry(he HistoryEntry) bool {
+	if len(he.Batch) > 0 {
+		cmds := make([]exec.Cmd, 0, len(he.Batch))
+		for _, cmd := range he.Batch {
+			cmds = append(cmds, exec.Cmd{Path: cmd.Path, Args: rerunArgs(cmd.Args), Dir: cmd.Dir})
+		}
+
+		outcome := runBatch(cmds)
+		logBatchHistory(cmds, outcome)
+		return outcome.Pass
+	}
+
+	// entries logged before runs used -json print their output as is
 	var outputWriter io.Writer = os.Stdout
-	if globalConfig.ColorizeOutput {
-		var colorReader io.Reader
-		colorReader, outputWriter = io.Pipe()
-		go colorizeOutput(colorReader)
+	var events *testEventWriter
+	if slices.Contains(he.Args, "-json") {
+		events = newTestEventWriter(outputWriter)
+		outputWriter = events
 	}
 
 	cmd := exec.Cmd{
 		Path:   he.Path,
-		Args:   he.Args,
+		Args:   rerunArgs(he.Args),
 		Dir:    he.Dir,
 		Stdout: outputWriter,
 		Stderr: os.Stderr,
@@ -253,8 +344,14 @@ func runHistoryEntry(he HistoryEntry) {
 
 	fmt.Println("Running", cmd.Args, "@", cmd.Dir)
 
+	// only the event stream can tell a hang apart from the rest of the output
+	run := cmd.Run
+	if events != nil {
+		run = func() error { return runCommand(&cmd, events) }
+	}
+
 	var pass bool
-	err := cmd.Run()
+	err := run()
 	var exit *exec.ExitError
 	switch {
 	case err == nil:
@@ -266,5 +363,29 @@ func runHistoryEntry(he HistoryEntry) {
 		panic(err)
 	}
 
-	logRunHistory(cmd, pass)
+	outcome := runOutcome{Pass: pass}
+	if events != nil {
+		events.Flush()
+		events.PrintSummary()
+		writeJUnitReport(events)
+		reportRaces(events)
+		outcome.Races = events.races
+		outcome.HangDump = reportHang(events)
+		storeLearnedTests(events.seen)
+		storeRunResults(he.Dir, events.takeResults())
+	}
+
+	logRunHistory(cmd, outcome)
+
+	return pass
+}
+
+// rerunArgs returns the arguments of a command from the history with the arguments given after -- added, IE
+// gotest -r -- -count=1. Debug sessions are rerun as they were.
+func rerunArgs(args []string) []string {
+	if len(passThroughArgs) == 0 || len(args) == 0 || args[0] != "go" {
+		return args
+	}
+
+	return append(slices.Clone(args), passThroughArgs...)
 }
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// junitTestSuites is the root of a JUnit XML report, there's a suite for every package in the run.
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	Timestamp string           `xml:"timestamp,attr,omitempty"`
+	TestCases []*junitTestCase `xml:"testcase"`
+	SystemOut string           `xml:"system-out,omitempty"`
+
+	elapsed float64
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitMessage is a failure or skip, message is the first file:line: message line logged and contents everything
+// logged.
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// fileLineMessage matches the lines t.Error, t.Skip and the compiler report, IE a_test.go:9: got <nil>.
+var fileLineMessage = regexp.MustCompile(`^\S+\.go:\d+(:\d+)?: `)
+
+// junitReport builds a JUnit XML report from the go test -json events of a run.
+type junitReport struct {
+	suites    []*junitTestSuite
+	byPackage map[string]*junitTestSuite
+	// cases are keyed by package and test, output is what each test logged and buildOutput what the compiler
+	// reported for each package that failed to build.
+	cases       map[string]*junitTestCase
+	output      map[string][]string
+	buildOutput map[string][]string
+}
+
+func newJUnitReport() *junitReport {
+	return &junitReport{
+		byPackage:   map[string]*junitTestSuite{},
+		cases:       map[string]*junitTestCase{},
+		output:      map[string][]string{},
+		buildOutput: map[string][]string{},
+	}
+}
+
+func (r *junitReport) add(ev testEvent) {
+	if ev.Action == "build-output" {
+		r.buildOutput[ev.ImportPath] = append(r.buildOutput[ev.ImportPath], ev.Output)
+		return
+	}
+
+	if ev.Package == "" {
+		return
+	}
+
+	suite := r.suite(ev)
+	key := ev.Package + " " + ev.Test
+
+	switch ev.Action {
+	case "run":
+		r.testCase(ev)
+
+	case "output":
+		if isStatusOutput(ev.Output) || strings.HasPrefix(ev.Output, "=== ") {
+			return
+		}
+
+		r.output[key] = append(r.output[key], ev.Output)
+
+	case "pass", "fail", "skip":
+		if ev.Test == "" {
+			suite.elapsed = ev.Elapsed
+			suite.Time = junitTime(ev.Elapsed)
+			suite.SystemOut = strings.Join(r.output[key], "")
+
+			// a package can fail without any of its tests failing, IE it didn't build or TestMain exited
+			if ev.Action == "fail" && suite.Failures == 0 {
+				output := r.output[key]
+				if ev.FailedBuild != "" {
+					output = r.buildOutput[ev.FailedBuild]
+				}
+
+				suite.TestCases = append(suite.TestCases, &junitTestCase{
+					Name:      ev.Package,
+					Classname: ev.Package,
+					Time:      junitTime(ev.Elapsed),
+					Failure:   newJUnitMessage("package failed", output),
+				})
+				suite.Tests++
+				suite.Failures++
+			}
+
+			return
+		}
+
+		testCase := r.testCase(ev)
+		testCase.Time = junitTime(ev.Elapsed)
+
+		output := r.output[key]
+		switch ev.Action {
+		case "fail":
+			testCase.Failure = newJUnitMessage("failed", output)
+			suite.Failures++
+		case "skip":
+			testCase.Skipped = newJUnitMessage("skipped", output)
+			suite.Skipped++
+		default:
+			testCase.SystemOut = strings.Join(output, "")
+		}
+
+		delete(r.output, key)
+	}
+}
+
+// suite returns the suite of the event's package, creating it the first time the package is seen.
+func (r *junitReport) suite(ev testEvent) *junitTestSuite {
+	suite, ok := r.byPackage[ev.Package]
+	if ok {
+		return suite
+	}
+
+	suite = &junitTestSuite{Name: ev.Package, Time: junitTime(0)}
+	if !ev.Time.IsZero() {
+		suite.Timestamp = ev.Time.Format(time.RFC3339)
+	}
+
+	r.byPackage[ev.Package] = suite
+	r.suites = append(r.suites, suite)
+
+	return suite
+}
+
+// testCase returns the case of the event's test, cases are listed in the order the tests started.
+func (r *junitReport) testCase(ev testEvent) *junitTestCase {
+	key := ev.Package + " " + ev.Test
+	testCase, ok := r.cases[key]
+	if ok {
+		return testCase
+	}
+
+	testCase = &junitTestCase{Name: ev.Test, Classname: ev.Package, Time: junitTime(0)}
+	r.cases[key] = testCase
+
+	suite := r.suite(ev)
+	suite.TestCases = append(suite.TestCases, testCase)
+	suite.Tests++
+
+	return testCase
+}
+
+// Marshal returns the report as XML, packages without tests are left out.
+func (r *junitReport) Marshal() ([]byte, error) {
+	report := junitTestSuites{}
+
+	var elapsed float64
+	for _, suite := range r.suites {
+		if len(suite.TestCases) == 0 {
+			continue
+		}
+
+		report.Suites = append(report.Suites, suite)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		elapsed += suite.elapsed
+	}
+
+	report.Time = junitTime(elapsed)
+
+	data, err := xml.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// writeJUnitReport writes the report of the run to the -junit path when one was given.
+func writeJUnitReport(events *testEventWriter) {
+	if *junitPath == "" || events.junit == nil {
+		return
+	}
+
+	data, err := events.junit.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	err = os.WriteFile(*junitPath, data, 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Wrote JUnit report to:", *junitPath)
+}
+
+func newJUnitMessage(fallback string, output []string) *junitMessage {
+	message := fallback
+	for _, line := range output {
+		line = strings.TrimSpace(line)
+		if fileLineMessage.MatchString(line) {
+			message = line
+			break
+		}
+
+		// without a file:line line, IE a panic or a race report, the last line is closest to the failure
+		if line != "" {
+			message = line
+		}
+	}
+
+	return &junitMessage{Message: message, Contents: strings.Join(output, "")}
+}
+
+func junitTime(elapsed float64) string {
+	return fmt.Sprintf("%.3f", elapsed)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_junitReport(t *testing.T) {
+	stream := `{"Time":"2026-01-02T03:04:05Z","Action":"start","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestA/case"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA/case","Output":"    a_test.go:9: got <nil>\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA/case","Output":"    --- FAIL: TestA/case (0.01s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestA/case","Elapsed":0.01}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestA","Elapsed":0.02}
+{"Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestB","Output":"    b_test.go:3: hello\n"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestB"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestC"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestC","Output":"    c_test.go:3: not on CI\n"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestC"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestD"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestD","Output":"==================\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestD","Output":"WARNING: DATA RACE\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestD","Output":"      /src/d_test.go:14 +0x44\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestD","Output":"==================\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestD","Output":"    testing.go:1465: race detected during execution of test\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestD","Elapsed":0.03}
+{"Action":"output","Package":"example.com/pkg","Output":"FAIL\n"}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.5}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"broken/b_test.go:5:28: undefined: x\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-fail"}
+{"Action":"start","Package":"example.com/broken"}
+{"Action":"fail","Package":"example.com/broken","FailedBuild":"example.com/broken [example.com/broken.test]"}
+{"Action":"skip","Package":"example.com/empty"}
+`
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="6" failures="4" skipped="1" time="0.500">
+	<testsuite name="example.com/pkg" tests="5" failures="3" skipped="1" time="0.500" timestamp="2026-01-02T03:04:05Z">
+		<testcase name="TestA" classname="example.com/pkg" time="0.020">
+			<failure message="failed"></failure>
+		</testcase>
+		<testcase name="TestA/case" classname="example.com/pkg" time="0.010">
+			<failure message="a_test.go:9: got &lt;nil&gt;">    a_test.go:9: got &lt;nil&gt;&#xA;</failure>
+		</testcase>
+		<testcase name="TestB" classname="example.com/pkg" time="0.000">
+			<system-out>    b_test.go:3: hello&#xA;</system-out>
+		</testcase>
+		<testcase name="TestC" classname="example.com/pkg" time="0.000">
+			<skipped message="c_test.go:3: not on CI">    c_test.go:3: not on CI&#xA;</skipped>
+		</testcase>
+		<testcase name="TestD" classname="example.com/pkg" time="0.030">
+			<failure message="testing.go:1465: race detected during execution of test">==================&#xA;WARNING: DATA RACE&#xA;      /src/d_test.go:14 +0x44&#xA;==================&#xA;    testing.go:1465: race detected during execution of test&#xA;</failure>
+		</testcase>
+	</testsuite>
+	<testsuite name="example.com/broken" tests="1" failures="1" skipped="0" time="0.000">
+		<testcase name="example.com/broken" classname="example.com/broken" time="0.000">
+			<failure message="broken/b_test.go:5:28: undefined: x">broken/b_test.go:5:28: undefined: x&#xA;</failure>
+		</testcase>
+	</testsuite>
+</testsuites>
+`
+
+	report := newJUnitReport()
+	w := newTestEventWriter(&strings.Builder{})
+	w.junit = report
+	w.Write([]byte(stream))
+	w.Flush()
+
+	data, err := report.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+}
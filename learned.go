@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// learnedBucket stores the names of the tests seen in go test runs, keyed by package import path. Subtests with
+// names that are only known at runtime can be selected once they've been run.
+const learnedBucket = "learned"
+
+// maxLearnedSubtests is how many subtests are remembered for each top level test, subtests named after random
+// values or timestamps get a new name every run so the least recently seen are forgotten.
+const maxLearnedSubtests = 50
+
+// storeLearnedTests records the tests that were run in each package along with when they were last seen.
+func storeLearnedTests(seen map[string]map[string]time.Time) {
+	if len(seen) == 0 {
+		return
+	}
+
+	db := getHistoryFile(historyFile)
+	defer db.Close()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(learnedBucket))
+		if err != nil {
+			return err
+		}
+
+		for pkg, tests := range seen {
+			learned := map[string]time.Time{}
+			if data := b.Get([]byte(pkg)); data != nil {
+				if err := json.Unmarshal(data, &learned); err != nil && *verbose {
+					fmt.Println("Ignoring invalid learned tests for", pkg, err)
+				}
+			}
+
+			for name, seenAt := range tests {
+				if seenAt.After(learned[name]) {
+					learned[name] = seenAt
+				}
+			}
+
+			pruneLearnedTests(learned, maxLearnedSubtests)
+
+			data, err := json.Marshal(learned)
+			if err != nil {
+				return err
+			}
+
+			err = b.Put([]byte(pkg), data)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// pruneLearnedTests removes all but the limit most recently seen subtests of each top level test from learned.
+func pruneLearnedTests(learned map[string]time.Time, limit int) {
+	subtests := map[string][]string{}
+	for name := range learned {
+		if strings.Contains(name, "/") {
+			subtests[topLevelName(name)] = append(subtests[topLevelName(name)], name)
+		}
+	}
+
+	for _, names := range subtests {
+		if len(names) <= limit {
+			continue
+		}
+
+		slices.SortFunc(names, func(a, b string) int {
+			if c := learned[b].Compare(learned[a]); c != 0 {
+				return c
+			}
+
+			return strings.Compare(a, b)
+		})
+
+		for _, name := range names[limit:] {
+			delete(learned, name)
+		}
+	}
+}
+
+// loadLearnedTests returns the tests learned for each of the packages.
+func loadLearnedTests(pkgs []string) map[string]map[string]time.Time {
+	learned := map[string]map[string]time.Time{}
+
+	db := getHistoryFile(historyFile)
+	defer db.Close()
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(learnedBucket))
+		if b == nil {
+			return nil
+		}
+
+		for _, pkg := range pkgs {
+			data := b.Get([]byte(pkg))
+			if data == nil {
+				continue
+			}
+
+			var tests map[string]time.Time
+			if err := json.Unmarshal(data, &tests); err != nil {
+				if *verbose {
+					fmt.Println("Ignoring invalid learned tests for", pkg, err)
+				}
+
+				continue
+			}
+
+			learned[pkg] = tests
+		}
+
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return learned
+}
+
+// mergeLearnedTests adds the learned tests that discovery didn't find, each one is listed after the other tests of
+// its top level test. Learned tests whose top level test no longer exists are dropped.
+func mergeLearnedTests(tests []Test) []Test {
+	pkgOf := make([]string, len(tests))
+	dirPkgs := map[string]string{}
+	for i, test := range tests {
+		dir := filepath.Dir(test.File)
+		pkg, ok := dirPkgs[dir]
+		if !ok {
+			pkg = importPath(dir, test.Module)
+			dirPkgs[dir] = pkg
+		}
+
+		pkgOf[i] = pkg
+	}
+
+	var pkgs []string
+	for _, pkg := range dirPkgs {
+		if pkg != "" {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	learned := loadLearnedTests(pkgs)
+	if len(learned) == 0 {
+		return tests
+	}
+
+	// learned subtests point at their top level test and are listed after the rest of its family
+	known := map[string]bool{}
+	tops := map[string]int{}
+	last := map[string]int{}
+	for i, test := range tests {
+		known[pkgOf[i]+" "+test.Name] = true
+		last[pkgOf[i]+" "+topLevelName(test.Name)] = i
+		if !strings.Contains(test.Name, "/") {
+			tops[pkgOf[i]+" "+test.Name] = i
+		}
+	}
+
+	extra := map[int][]Test{}
+	for pkg, names := range learned {
+		for name, seenAt := range names {
+			top, ok := tops[pkg+" "+topLevelName(name)]
+			if !ok || known[pkg+" "+name] {
+				continue
+			}
+
+			after := last[pkg+" "+topLevelName(name)]
+			extra[after] = append(extra[after], Test{
+				File:       tests[top].File,
+				Name:       name,
+				FilePath:   tests[top].FilePath,
+				LineNumber: tests[top].LineNumber,
+				Module:     tests[top].Module,
+				Learned:    true,
+				LastSeen:   seenAt,
+			})
+		}
+	}
+
+	merged := make([]Test, 0, len(tests))
+	for i, test := range tests {
+		merged = append(merged, test)
+
+		learnedTests := extra[i]
+		slices.SortFunc(learnedTests, func(a, b Test) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+
+		merged = append(merged, learnedTests...)
+	}
+
+	return merged
+}
+
+// importPath returns the import path of the package in dir, which belongs to module.
+func importPath(dir, module string) string {
+	if module == "" {
+		return ""
+	}
+
+	modRoot := lookupModuleRoot(dir)
+	if modRoot == "" {
+		return ""
+	}
+
+	rel, err := filepath.Rel(modRoot, dir)
+	if err != nil {
+		return ""
+	}
+
+	if rel == "." {
+		return module
+	}
+
+	return module + "/" + filepath.ToSlash(rel)
+}
+
+func topLevelName(name string) string {
+	top, _, _ := strings.Cut(name, "/")
+	return top
+}
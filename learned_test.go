@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mergeLearnedTests(t *testing.T) {
+	isolateHistory(t)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/root\n\ngo 1.21\n")
+	file := filepath.Join(dir, "sub", "a_test.go")
+
+	seenAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	storeLearnedTests(map[string]map[string]time.Time{
+		"example.com/root/sub": {
+			"TestA":            seenAt,
+			"TestA/static":     seenAt,
+			"TestA/dynamic-42": seenAt,
+			"TestGone/old":     seenAt,
+		},
+	})
+
+	discovered := []Test{
+		{File: file, Name: "TestA", FilePath: file, LineNumber: 5, Module: "example.com/root"},
+		{File: file, Name: "TestA/static", FilePath: file, LineNumber: 6, Module: "example.com/root"},
+		{File: file, Name: "TestB", FilePath: file, LineNumber: 10, Module: "example.com/root"},
+	}
+
+	expected := []Test{
+		discovered[0],
+		discovered[1],
+		{File: file, Name: "TestA/dynamic-42", FilePath: file, LineNumber: 5, Module: "example.com/root", Learned: true, LastSeen: seenAt},
+		discovered[2],
+	}
+
+	merged := mergeLearnedTests(discovered)
+	for i := range merged {
+		merged[i].LastSeen = merged[i].LastSeen.UTC()
+	}
+
+	assert.Equal(t, expected, merged)
+}
+
+func Test_pruneLearnedTests(t *testing.T) {
+	at := func(minute int) time.Time {
+		return time.Date(2024, 1, 2, 3, minute, 0, 0, time.UTC)
+	}
+
+	learned := map[string]time.Time{
+		"TestA":         at(0),
+		"TestA/run-1":   at(1),
+		"TestA/run-2":   at(2),
+		"TestA/run-3":   at(3),
+		"TestA/run-3/x": at(3),
+		"TestB/static":  at(0),
+	}
+
+	pruneLearnedTests(learned, 2)
+
+	assert.Equal(t, map[string]time.Time{
+		"TestA":         at(0),
+		"TestA/run-3":   at(3),
+		"TestA/run-3/x": at(3),
+		"TestB/static":  at(0),
+	}, learned)
+}
+
+func Test_storeLearnedTestsPrunes(t *testing.T) {
+	isolateHistory(t)
+
+	seen := map[string]time.Time{}
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < maxLearnedSubtests+5; i++ {
+		seen[fmt.Sprintf("TestA/run-%d", i)] = start.Add(time.Duration(i) * time.Second)
+	}
+
+	storeLearnedTests(map[string]map[string]time.Time{"example.com/pkg": seen})
+
+	learned := loadLearnedTests([]string{"example.com/pkg"})["example.com/pkg"]
+	assert.Len(t, learned, maxLearnedSubtests)
+	assert.NotContains(t, learned, "TestA/run-4")
+	assert.Contains(t, learned, "TestA/run-5")
+}
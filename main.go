@@ -1,13 +1,12 @@
 package main
 
 import (
-	"bufio"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/MordFustang21/gotest/pkg/flamegraph"
@@ -17,42 +16,127 @@ import (
 var (
 	flagSet = flag.NewFlagSet("gotest", flag.ExitOnError)
 	// Flags for the program
-	subtest           = flagSet.Bool("s", false, "Run a specific subtest")
+	subtest           = flagSet.Bool("s", false, "Run a specific subtest, gotest -s <query> runs the test matching the query")
+	noInteractive     = flagSet.Bool("no-interactive", false, "With -s <query> fail listing the matches instead of opening the selector when there's more than one")
+	at                = flagSet.String("at", "", "Run the innermost test, subtest or table row containing a file:line position, IE foo_test.go:42")
+	stdio             = flagSet.Bool("stdio", false, "With serve, answer JSON-RPC requests on stdin and stdout, IE gotest serve --stdio")
 	verbose           = flagSet.Bool("verbose", false, "Print verbose output")
 	debug             = flagSet.Bool("d", false, "Run test in debug mode with delve")
 	quiet             = flagSet.Bool("q", false, "Disables verbose output on go test")
 	runFromHistory    = flagSet.Bool("his", false, "Run a specific command from the history")
 	rerun             = flagSet.Bool("r", false, "Re-run the last test")
+	rerunFailures     = flagSet.Bool("rf", false, "Re-run only the tests that failed in the last run")
+	untilGreen        = flagSet.Int("until-green", 0, "With -rf keep rerunning the tests still failing up to N more times")
+	raceDetector      = flagSet.Bool("race", false, "Run with the race detector and summarize the data races found")
+	hangAfter         = flagSet.Duration("hang", 0, "Capture a goroutine dump when the tests write nothing for this long, IE 30s")
+	junitPath         = flagSet.String("junit", "", "Write a JUnit XML report of the run to the given path")
+	flaky             = flagSet.Int("flaky", 0, "Run a specific test N times and report how often and why it fails")
+	watch             = flagSet.Bool("w", false, "Watch for changes and rerun tests, combine with -s or -r to pick them")
+	changed           = flagSet.Bool("changed", false, "Run the tests of packages affected by uncommitted git changes")
+	changedStaged     = flagSet.Bool("staged", false, "With -changed only consider staged changes")
+	changedBase       = flagSet.String("base", "", "With -changed compare against the point the branch forked from a ref, IE origin/main")
 	benchmark         = flagSet.Bool("b", false, "Run a specific benchmark.")
+	fuzz              = flagSet.Bool("fuzz", false, "Fuzz a specific fuzz test or replay one of its inputs")
+	example           = flagSet.Bool("example", false, "Run a specific example")
 	withCoverage      = flagSet.Bool("cover", false, "Run the test with coverage and auto launch the viewer")
 	withCPUProfile    = flagSet.Bool("cpu", false, "Run the test with a CPU profile")
 	withMemoryProfile = flagSet.Bool("mem", false, "Run the test with a memory profile")
 )
 
 func main() {
-	flagSet.Parse(os.Args[1:])
+	args, extra := splitPassThrough(os.Args[1:])
+	args = parseFlags(args)
+	passThroughArgs = extra
 	
 	err := loadConfig(globalConfig, WithDefaultConfig())
 	if err != nil {
 		panic(err)
 	}
 
-	err = run()
+	err = run(args)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
-func run() error {
-	readDir := flag.Arg(0)
-	if readDir == "" {
-		readDir, _ = os.Getwd()
+// parseFlags parses the flags wherever they are among the arguments, IE gotest -s TestName -no-interactive, and
+// returns the other arguments.
+func parseFlags(args []string) []string {
+	var positional []string
+	for {
+		flagSet.Parse(args)
+		if flagSet.NArg() == 0 {
+			return positional
+		}
+
+		positional = append(positional, flagSet.Arg(0))
+		args = flagSet.Args()[1:]
+	}
+}
+
+func run(args []string) error {
+	// editors run gotest as a server to list, run and debug tests through it
+	if len(args) > 0 && args[0] == "serve" {
+		if !*stdio {
+			return errors.New("serve only speaks JSON-RPC over stdin and stdout, run gotest serve --stdio")
+		}
+
+		return serveStdio()
+	}
+
+	var readDir string
+	if len(args) > 0 {
+		readDir = args[0]
+	}
+
+	// -s takes a query to pick tests by instead of a directory, though a directory can still come first
+	var query string
+	if *subtest && readDir != "" {
+		if info, err := os.Stat(readDir); err != nil || !info.IsDir() {
+			readDir = ""
+		} else {
+			args = args[1:]
+		}
+
+		query = strings.Join(args, " ")
+	}
+
+	// the history is looked up by absolute directories
+	readDir, err := filepath.Abs(readDir)
+	if err != nil {
+		return fmt.Errorf("error resolving directory: %w", err)
 	}
 
 	switch {
+	case *watch:
+		return watchTests(readDir)
+
+	case *flaky > 0:
+		availableTests, err := getTestsFromDir(readDir, kindTest)
+		if err != nil {
+			return fmt.Errorf("error getting tests: %w", err)
+		}
+
+		if len(availableTests) == 0 {
+			fmt.Println("No tests found in the directory")
+			return nil
+		}
+
+		selected := selectTest(mergeLearnedTests(availableTests))
+		runFlaky(selected, *flaky)
+
+	case *rerunFailures:
+		return rerunFailed(readDir, *untilGreen)
+
+	case *changed:
+		return runChanged(readDir)
+
+	case *at != "":
+		return runAt(*at)
+
 	case *subtest:
-		availableTests, err := getTestsFromDir(readDir, false)
+		availableTests, err := getTestsFromDir(readDir, kindTest)
 		if err != nil {
 			return fmt.Errorf("error getting tests: %w", err)
 		}
@@ -62,12 +146,33 @@ func run() error {
 			return nil
 		}
 
-		// select a test file and testToRun
-		testToRun := selectTest(availableTests)
+		// subtests seen in earlier runs that discovery can't find are listed too
+		availableTests = mergeLearnedTests(availableTests)
+
+		if query != "" {
+			matches, best := matchTests(availableTests, query)
+			switch {
+			case best == 1:
+				return runTests(matches[:1])
+			case *noInteractive && len(matches) == 0:
+				return fmt.Errorf("no tests match %q", query)
+			case *noInteractive:
+				fmt.Printf("%d tests match %q:\n", len(matches), query)
+				for _, t := range matches {
+					fmt.Println(t.Name)
+				}
+
+				return errors.New("more than one test matches, refine the query")
+			}
+		} else if *noInteractive {
+			return errors.New("-no-interactive needs a query to pick the test by, IE gotest -s -no-interactive TestName")
+		}
 
-		// execute the test
-		cmd, pass := executeTests(testToRun)
-		logRunHistory(cmd, pass)
+		// select the tests to run
+		testsToRun := selectTests(availableTests, query)
+
+		// execute the tests
+		return runTests(testsToRun)
 
 	case *rerun:
 		he, err := getLastCommand()
@@ -75,10 +180,12 @@ func run() error {
 			return fmt.Errorf("error getting last command: %w", err)
 		}
 
-		runHistoryEntry(he)
+		if !runHistoryEntry(he) {
+			return errTestsFailed
+		}
 
 	case *benchmark:
-		benchmarks, err := getTestsFromDir(readDir, true)
+		benchmarks, err := getTestsFromDir(readDir, kindBenchmark)
 		if err != nil {
 			return fmt.Errorf("error getting benchmarks: %w", err)
 		}
@@ -91,19 +198,81 @@ func run() error {
 		selected := selectTest(benchmarks)
 		runBenchmark(selected)
 
+	case *fuzz:
+		fuzzTests, err := getTestsFromDir(readDir, kindFuzz)
+		if err != nil {
+			return fmt.Errorf("error getting fuzz tests: %w", err)
+		}
+
+		if len(fuzzTests) == 0 {
+			fmt.Println("No fuzz tests found in the directory")
+			return nil
+		}
+
+		selected := selectTest(fuzzTests)
+		runFuzz(selected)
+
+	case *example:
+		examples, err := getTestsFromDir(readDir, kindExample)
+		if err != nil {
+			return fmt.Errorf("error getting examples: %w", err)
+		}
+
+		if len(examples) == 0 {
+			fmt.Println("No examples found in the directory")
+			return nil
+		}
+
+		selected := selectTest(examples)
+		cmd, pass := runExample(selected)
+		logRunHistory(cmd, runOutcome{Pass: pass})
+
 	case *runFromHistory:
 		he, err := selectHistory()
 		if err != nil {
 			return fmt.Errorf("error selecting history: %w", err)
 		}
 
-		runHistoryEntry(he)
+		if !runHistoryEntry(he) {
+			return errTestsFailed
+		}
 
 	default:
 		// run a test for the directory
-		cmd, pass := executeTests(Test{File: readDir})
-		logRunHistory(cmd, pass)
+		cmd, outcome := executeTests(Test{File: readDir})
+		logRunHistory(cmd, outcome)
+		if !outcome.Pass {
+			return errTestsFailed
+		}
+	}
+
+	return nil
+}
+
+// errTestsFailed is returned when the tests run fail, so gotest exits non-zero like go test for scripts and editors.
+var errTestsFailed = errors.New("tests failed")
+
+// runTests runs the tests and logs them to the history, tests from several packages are run as a batch. It returns
+// errTestsFailed when they don't all pass.
+func runTests(tests []Test) error {
+	if len(tests) == 1 {
+		cmd, outcome := executeTests(tests[0])
+		logRunHistory(cmd, outcome)
+		if !outcome.Pass {
+			return errTestsFailed
+		}
+
+		return nil
+	}
 
+	cmds, outcome, err := executeBatch(tests)
+	if err != nil {
+		return fmt.Errorf("error running tests: %w", err)
+	}
+
+	logBatchHistory(cmds, outcome)
+	if !outcome.Pass {
+		return errTestsFailed
 	}
 
 	return nil
@@ -115,9 +284,15 @@ func selectTest(availableTests []Test) Test {
 		Items: availableTests,
 		Templates: &promptui.SelectTemplates{
 			Label:    "{{ .File }}",
-			Active:   "> {{ .Name }}",
-			Inactive: "  {{ .Name }}",
+			Active:   "> {{ .Name }}{{ if .Learned }} (learned){{ end }}",
+			Inactive: "  {{ .Name }}{{ if .Learned }} (learned){{ end }}",
 			Selected: "{{ .Name }}",
+			Details: `{{ .FilePath }}:{{ .LineNumber }}{{ if .Module }} ({{ .Module }}){{ end }}
+{{- if .Learned }}
+Learned from a previous run, last seen {{ .LastSeen.Format "01/02/2006 @ 15:04:05" }}{{ end }}
+{{- if .IsExample }}
+Output:
+{{ .ExpectedOutput }}{{ end }}`,
 		},
 		Searcher: func(input string, index int) bool {
 			test := availableTests[index]
@@ -139,19 +314,19 @@ func selectTest(availableTests []Test) Test {
 	return Test{}
 }
 
-// executeTests will run the test and return the command and if it passed.
-func executeTests(t Test) (exec.Cmd, bool) {
+// executeTests will run the test and return the command and its outcome.
+func executeTests(t Test) (exec.Cmd, runOutcome) {
 	path, modRoot := testToPathAndRoot(t)
 
-	args := []string{"test", quietMode(), path}
-	if t.Name != "" {
-		args = append(args, "-run", t.Name)
-	}
+	args := testArgs(path, modRoot, []Test{t})
 
 	if *debug {
 		return debugTest(t, path, modRoot)
 	}
 
+	// the event stream is decoded back into the usual output, recording the name of every test that runs
+	args = append(args, "-json")
+
 	var coverFile string
 	if *withCoverage {
 		tempFile, err := os.CreateTemp("", "go-test_"+t.Name)
@@ -196,26 +371,20 @@ func executeTests(t Test) (exec.Cmd, bool) {
 		panic(err)
 	}
 
-	var outputWriter io.Writer = os.Stdout
-	if globalConfig.ColorizeOutput {
-		var colorReader io.Reader
-		colorReader, outputWriter = io.Pipe()
-		go colorizeOutput(colorReader)
-	}
-
+	events := newTestEventWriter(os.Stdout)
 	cmd := exec.Cmd{
 		Path:   p,
 		Env:    os.Environ(),
 		Args:   append([]string{"go"}, args...),
 		Dir:    modRoot,
-		Stdout: outputWriter,
+		Stdout: events,
 		Stderr: os.Stderr,
 	}
 
 	fmt.Println("Running", cmd.Args, "@", cmd.Dir)
 
 	var pass bool
-	err = cmd.Run()
+	err = runCommand(&cmd, events)
 	var exit *exec.ExitError
 	switch {
 	case err == nil:
@@ -226,6 +395,14 @@ func executeTests(t Test) (exec.Cmd, bool) {
 		panic(err)
 	}
 
+	events.Flush()
+	events.PrintSummary()
+	writeJUnitReport(events)
+	reportRaces(events)
+	outcome := runOutcome{Pass: pass, Races: events.races, HangDump: reportHang(events)}
+	storeLearnedTests(events.seen)
+	storeRunResults(modRoot, events.takeResults())
+
 	// if coverage was enabled launch the UI to view it
 	if *withCoverage {
 		cmd := exec.Cmd{
@@ -280,27 +457,7 @@ func executeTests(t Test) (exec.Cmd, bool) {
 		}
 	}
 
-	return cmd, pass
-}
-
-func colorizeOutput(in io.Reader) {
-	rdr := bufio.NewReader(in)
-	for {
-		line, err := rdr.ReadString('\n')
-		if err != nil {
-			panic(err)
-		}
-
-		switch {
-		case strings.Contains(line, "FAIL"):
-			fmt.Print("\033[31m")
-		case strings.Contains(line, "PASS"):
-			fmt.Print("\033[32m")
-		}
-
-		fmt.Print(line)
-		fmt.Print("\033[0m")
-	}
+	return cmd, outcome
 }
 
 // quietMode will return a string that can be used to suppress output.
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"unicode"
+
+	"github.com/chzyer/readline"
+	"github.com/manifoldco/promptui"
+	"github.com/manifoldco/promptui/list"
+	"github.com/manifoldco/promptui/screenbuf"
+)
+
+// selectTests lets the user pick any number of tests. Typing filters the list, starting from query, space toggles
+// the test under the cursor, ctrl+a toggles every test matching the filter and enter runs the toggled tests, or the
+// test under the cursor when none are toggled.
+func selectTests(availableTests []Test, query string) []Test {
+	matches := func(filter string, index int) bool {
+		return fuzzyMatch(availableTests[index].Name, filter)
+	}
+
+	// the list holds indexes into availableTests so toggled tests can be tracked while the list is filtered
+	indexes := make([]int, len(availableTests))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	l, err := list.New(indexes, 10)
+	if err != nil {
+		panic(err)
+	}
+
+	l.Searcher = matches
+	if query != "" {
+		l.Search(query)
+	}
+
+	// current returns the index of the test under the cursor, list.Index panics when the filter matches nothing
+	current := func() int {
+		items, cursor := l.Items()
+		if cursor == list.NotFound {
+			return list.NotFound
+		}
+
+		return items[cursor].(int)
+	}
+
+	c := &readline.Config{}
+	err = c.Init()
+	if err != nil {
+		panic(err)
+	}
+
+	c.Stdin = readline.NewCancelableStdin(c.Stdin)
+	c.HistoryLimit = -1
+	c.UniqueEditLine = true
+
+	rl, err := readline.NewEx(c)
+	if err != nil {
+		panic(err)
+	}
+
+	// hide the cursor while selecting
+	rl.Write([]byte("\033[?25l"))
+	sb := screenbuf.New(rl)
+
+	filter := []rune(query)
+	selected := map[int]bool{}
+	c.SetListener(func(_ []rune, _ int, key rune) ([]rune, int, bool) {
+		switch key {
+		case promptui.KeyEnter:
+			return nil, 0, true
+		case promptui.KeyNext:
+			l.Next()
+		case promptui.KeyPrev:
+			l.Prev()
+		case promptui.KeyForward:
+			l.PageDown()
+		case promptui.KeyBackward:
+			l.PageUp()
+		case ' ':
+			if index := current(); index != list.NotFound {
+				selected[index] = !selected[index]
+			}
+		case readline.CharLineStart:
+			// select every match, or clear them when they're all selected already
+			var indexes []int
+			allSelected := true
+			for i := range availableTests {
+				if matches(string(filter), i) {
+					indexes = append(indexes, i)
+					allSelected = allSelected && selected[i]
+				}
+			}
+
+			for _, i := range indexes {
+				selected[i] = !allSelected
+			}
+		case readline.CharBackspace, promptui.KeyCtrlH:
+			if len(filter) > 0 {
+				filter = filter[:len(filter)-1]
+			}
+
+			if len(filter) == 0 {
+				l.CancelSearch()
+			} else {
+				l.Search(string(filter))
+			}
+		default:
+			if unicode.IsPrint(key) {
+				filter = append(filter, key)
+				l.Search(string(filter))
+			}
+		}
+
+		sb.WriteString("Select tests (space toggles, ctrl+a toggles all matches, enter runs)")
+		sb.WriteString("Search: " + string(filter))
+
+		items, cursor := l.Items()
+		for i, item := range items {
+			index := item.(int)
+			test := availableTests[index]
+
+			pointer := "  "
+			if i == cursor {
+				pointer = "> "
+			}
+
+			check := "[ ]"
+			if selected[index] {
+				check = "[x]"
+			}
+
+			line := pointer + check + " " + test.Name
+			if test.Learned {
+				line += " (learned)"
+			}
+
+			sb.WriteString(line)
+		}
+
+		if cursor == list.NotFound {
+			sb.WriteString("No results")
+		} else {
+			test := availableTests[items[cursor].(int)]
+			details := fmt.Sprintf("%s:%d", test.FilePath, test.LineNumber)
+			if test.Module != "" {
+				details += " (" + test.Module + ")"
+			}
+
+			sb.WriteString(details)
+		}
+
+		sb.WriteString(fmt.Sprintf("%d selected", countSelected(selected)))
+		sb.Flush()
+
+		return nil, 0, true
+	})
+
+	for {
+		_, err = rl.Readline()
+		if err != nil {
+			break
+		}
+
+		// enter with nothing toggled and nothing under the cursor does nothing
+		if countSelected(selected) > 0 || current() != list.NotFound {
+			break
+		}
+	}
+
+	var chosen []Test
+	if err == nil {
+		for i, test := range availableTests {
+			if selected[i] {
+				chosen = append(chosen, test)
+			}
+		}
+
+		if len(chosen) == 0 {
+			chosen = append(chosen, availableTests[current()])
+		}
+	}
+
+	sb.Reset()
+	for _, test := range chosen {
+		sb.WriteString(test.Name)
+	}
+	sb.Flush()
+
+	rl.Write([]byte("\033[?25h"))
+	rl.Close()
+
+	switch {
+	case err == nil:
+		return chosen
+	case err == readline.ErrInterrupt:
+		fmt.Println("No Test Selected")
+		os.Exit(0)
+	default:
+		panic(err)
+	}
+
+	return nil
+}
+
+func countSelected(selected map[int]bool) int {
+	var n int
+	for _, isSelected := range selected {
+		if isSelected {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// subtestNamer names subtests the same way the testing package does so that discovered names match the names
+// reported by go test.
+type subtestNamer struct {
+	// seen tracks how many times a full subtest name has been used.
+	seen map[string]int
+}
+
+func newSubtestNamer() *subtestNamer {
+	return &subtestNamer{seen: map[string]int{}}
+}
+
+// name returns the unique name of subname run within parent and if it was renamed because of a duplicate.
+// Duplicate names get a #01, #02... suffix and empty names become #00 just like testing.(*matcher).unique.
+func (n *subtestNamer) name(parent, subname string) (string, bool) {
+	name := fmt.Sprintf("%s/%s", parent, rewriteSubtestName(subname))
+	empty := subname == ""
+	var duplicate bool
+	for {
+		next, exists := n.seen[name]
+		if !empty && !exists {
+			n.seen[name] = 1
+			return name, duplicate
+		}
+
+		// Name was already used. Increment the count and append it to discriminate between duplicate names.
+		n.seen[name] = next + 1
+		name = fmt.Sprintf("%s#%02d", name, next)
+		empty = false
+		duplicate = duplicate || exists
+	}
+}
+
+// rewriteSubtestName rewrites a subtest name the same way testing does, spaces become underscores and non
+// printable characters are escaped.
+func rewriteSubtestName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case isSpace(r):
+			b.WriteByte('_')
+		case !strconv.IsPrint(r):
+			q := strconv.QuoteRune(r)
+			b.WriteString(q[1 : len(q)-1])
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// isSpace matches the definition of a space used by the testing package, which is not the same as unicode.IsSpace.
+func isSpace(r rune) bool {
+	if r < 0x2000 {
+		switch r {
+		case '\t', '\n', '\v', '\f', '\r', ' ', 0x85, 0xA0, 0x1680:
+			return true
+		}
+
+		return false
+	}
+
+	if r <= 0x200a {
+		return true
+	}
+
+	switch r {
+	case 0x2028, 0x2029, 0x202f, 0x205f, 0x3000:
+		return true
+	}
+
+	return false
+}
+
+// runPattern builds a -run/-bench pattern that matches exactly the test name. Every slash separated segment is
+// matched on its own by go test so each one is quoted and anchored.
+func runPattern(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = "^" + regexp.QuoteMeta(segment) + "$"
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// testRunPattern builds the -run pattern for t. Subtests whose names were only partly resolved match anything in
+// place of each * in the name.
+func testRunPattern(t Test) string {
+	if !t.IsWildcard {
+		return runPattern(t.Name)
+	}
+
+	segments := strings.Split(t.Name, "/")
+	for i, segment := range segments {
+		parts := strings.Split(segment, "*")
+		for j, part := range parts {
+			parts[j] = regexp.QuoteMeta(part)
+		}
+
+		segments[i] = "^" + strings.Join(parts, ".*") + "$"
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_subtestNamer(t *testing.T) {
+	tests := []struct {
+		name     string
+		subnames []string
+		expected []string
+	}{
+		{
+			name:     "spaces",
+			subnames: []string{"basic with comment", "tab\there"},
+			expected: []string{"TestX/basic_with_comment", "TestX/tab_here"},
+		},
+		{
+			name:     "duplicates",
+			subnames: []string{"dup", "dup", "dup"},
+			expected: []string{"TestX/dup", "TestX/dup#01", "TestX/dup#02"},
+		},
+		{
+			name:     "empty",
+			subnames: []string{"", ""},
+			expected: []string{"TestX/#00", "TestX/#01"},
+		},
+		{
+			name:     "non printable",
+			subnames: []string{"bell\a"},
+			expected: []string{`TestX/bell\a`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namer := newSubtestNamer()
+
+			var names []string
+			for _, subname := range tt.subnames {
+				name, _ := namer.name("TestX", subname)
+				names = append(names, name)
+			}
+
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func Test_testRunPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		wildcard bool
+		expected string
+		matches  []string
+		misses   []string
+	}{
+		{
+			name:     "TestX",
+			expected: "^TestX$",
+			matches:  []string{"TestX"},
+			misses:   []string{"TestXY"},
+		},
+		{
+			name:     "TestX/case_(a+b).go",
+			expected: `^TestX$/^case_\(a\+b\)\.go$`,
+			matches:  []string{"TestX/case_(a+b).go"},
+			misses:   []string{"TestX/case_aab_go", "TestX/case_(a+b).go2"},
+		},
+		{
+			name:     "TestX/dup#01",
+			expected: "^TestX$/^dup#01$",
+			matches:  []string{"TestX/dup#01"},
+			misses:   []string{"TestX/dup"},
+		},
+		{
+			name:     "TestX/rand-*",
+			wildcard: true,
+			expected: "^TestX$/^rand-.*$",
+			matches:  []string{"TestX/rand-1", "TestX/rand-"},
+			misses:   []string{"TestX/other-1", "TestY/rand-1"},
+		},
+		{
+			name:     "TestX/a*b",
+			expected: `^TestX$/^a\*b$`,
+			matches:  []string{"TestX/a*b"},
+			misses:   []string{"TestX/aab"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := testRunPattern(Test{Name: tt.name, IsWildcard: tt.wildcard})
+			assert.Equal(t, tt.expected, pattern)
+
+			for _, name := range tt.matches {
+				assert.True(t, matchRunPattern(pattern, name), name)
+			}
+
+			for _, name := range tt.misses {
+				assert.False(t, matchRunPattern(pattern, name), name)
+			}
+		})
+	}
+}
+
+// matchRunPattern matches a test name the same way go test does, segment by segment.
+func matchRunPattern(pattern, name string) bool {
+	patterns := strings.Split(pattern, "/")
+	names := strings.Split(name, "/")
+	if len(names) < len(patterns) {
+		return false
+	}
+
+	for i, p := range patterns {
+		if !regexp.MustCompile(p).MatchString(names[i]) {
+			return false
+		}
+	}
+
+	return true
+}
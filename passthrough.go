@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// passThroughArgs are the arguments after -- on the command line, they're added to go test as is. IE
+// gotest -s -- -race -count=1
+var passThroughArgs []string
+
+// boolFlags are the go test and build flags that don't take a value, any other flag without = takes the next
+// argument as its value.
+var boolFlags = []string{"a", "asan", "benchmem", "cover", "failfast", "fullpath", "json", "linkshared",
+	"modcacherw", "msan", "n", "race", "short", "trimpath", "v", "work", "x"}
+
+// testBinaryFlags are the go test flags that are handled by the test binary rather than the build, see
+// go help testflag. dlv needs them passed to the binary with a test. prefix.
+var testBinaryFlags = []string{"bench", "benchmem", "benchtime", "blockprofile", "blockprofilerate", "count",
+	"coverprofile", "cpu", "cpuprofile", "failfast", "fullpath", "fuzz", "fuzzminimizetime", "fuzztime", "list",
+	"memprofile", "memprofilerate", "mutexprofile", "mutexprofilefraction", "outputdir", "parallel", "run", "short",
+	"shuffle", "skip", "timeout", "trace", "v"}
+
+// splitPassThrough splits the command line arguments at the first --, the flags before it are gotest's own.
+func splitPassThrough(args []string) ([]string, []string) {
+	i := slices.Index(args, "--")
+	if i < 0 {
+		return args, nil
+	}
+
+	return args[:i], args[i+1:]
+}
+
+// extraArgs returns the arguments added to every go test command run in the module at modRoot. Those are -race
+// with -race, the ExtraFlags from the config, the ProjectFlags configured for the module and the arguments given
+// after --.
+func extraArgs(modRoot string) []string {
+	var args []string
+	if *raceDetector {
+		args = append(args, "-race")
+	}
+
+	args = append(args, strings.Fields(globalConfig.ExtraFlags)...)
+
+	for dir, flags := range globalConfig.ProjectFlags {
+		if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(dir, "~/") {
+			dir = filepath.Join(home, dir[2:])
+		}
+
+		if filepath.Clean(dir) == filepath.Clean(modRoot) {
+			args = append(args, strings.Fields(flags)...)
+		}
+	}
+
+	return append(args, passThroughArgs...)
+}
+
+// dlvArgs splits go test arguments into the flags dlv builds with and the flags given to the test binary, which
+// need the test. prefix since the binary is run directly.
+func dlvArgs(args []string) ([]string, []string) {
+	var build, test []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+		// a flag given as -flag value has its value in the next argument
+		group := []string{arg}
+		if !hasValue && strings.HasPrefix(arg, "-") && !slices.Contains(boolFlags, name) && i+1 < len(args) {
+			group = append(group, args[i+1])
+			i++
+		}
+
+		if !strings.HasPrefix(arg, "-") || !slices.Contains(testBinaryFlags, name) {
+			build = append(build, group...)
+			continue
+		}
+
+		group[0] = "-test." + strings.TrimLeft(arg, "-")
+		test = append(test, group...)
+	}
+
+	return build, test
+}
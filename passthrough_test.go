@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_splitPassThrough(t *testing.T) {
+	flags, extra := splitPassThrough([]string{"-s", "./pkg", "--", "-race", "--", "-count=1"})
+	assert.Equal(t, []string{"-s", "./pkg"}, flags)
+	assert.Equal(t, []string{"-race", "--", "-count=1"}, extra)
+
+	flags, extra = splitPassThrough([]string{"-r"})
+	assert.Equal(t, []string{"-r"}, flags)
+	assert.Nil(t, extra)
+}
+
+func Test_extraArgs(t *testing.T) {
+	config := *globalConfig
+	t.Cleanup(func() {
+		*globalConfig = config
+		passThroughArgs = nil
+	})
+
+	home, err := os.UserHomeDir()
+	assert.NoError(t, err)
+
+	globalConfig.ExtraFlags = "-race"
+	globalConfig.ProjectFlags = map[string]string{
+		"~/src/api": "-tags=integration -timeout 5m",
+		"/src/web":  "-short",
+	}
+	passThroughArgs = []string{"-count=1"}
+
+	assert.Equal(t, []string{"-race", "-tags=integration", "-timeout", "5m", "-count=1"}, extraArgs(filepath.Join(home, "src", "api")))
+	assert.Equal(t, []string{"-race", "-short", "-count=1"}, extraArgs("/src/web/"))
+	assert.Equal(t, []string{"-race", "-count=1"}, extraArgs("/src/other"))
+}
+
+func Test_dlvArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		build []string
+		test  []string
+	}{
+		{
+			name:  "build and test flags",
+			args:  []string{"-race", "-count=1", "-tags", "integration", "-short"},
+			build: []string{"-race", "-tags", "integration"},
+			test:  []string{"-test.count=1", "-test.short"},
+		},
+		{
+			name: "test flag with separate value",
+			args: []string{"--timeout", "5m", "-failfast", "-shuffle=on"},
+			test: []string{"-test.timeout", "5m", "-test.failfast", "-test.shuffle=on"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build, test := dlvArgs(tt.args)
+			assert.Equal(t, tt.build, build)
+			assert.Equal(t, tt.test, test)
+		})
+	}
+}
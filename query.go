@@ -0,0 +1,100 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// match tiers, a better match has a higher tier.
+const (
+	noMatch = iota
+	subsequenceMatch
+	substringMatch
+	segmentMatch
+	exactMatch
+)
+
+// queryMatch is how well a test matches a query.
+type queryMatch struct {
+	test Test
+	tier int
+	// span is how much of the name the match covers, tighter matches are listed first.
+	span int
+}
+
+// matchTests returns the tests matching query, best first, and how many of them are the best match. A test matches
+// when its name equals the query, one of the segments of its name does, its name contains the query or the letters
+// of the query appear in order in its name. Matching ignores case and treats spaces like go test does.
+func matchTests(tests []Test, query string) ([]Test, int) {
+	var matches []queryMatch
+	for _, t := range tests {
+		tier, span := matchQuery(t.Name, query)
+		if tier != noMatch {
+			matches = append(matches, queryMatch{test: t, tier: tier, span: span})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b queryMatch) int {
+		if a.tier != b.tier {
+			return b.tier - a.tier
+		}
+
+		return a.span - b.span
+	})
+
+	var best int
+	sorted := make([]Test, 0, len(matches))
+	for _, m := range matches {
+		if m.tier == matches[0].tier {
+			best++
+		}
+
+		sorted = append(sorted, m.test)
+	}
+
+	return sorted, best
+}
+
+// matchQuery returns the tier of the match of name against query and how many characters of name it spans.
+func matchQuery(name, query string) (int, int) {
+	name = strings.ToLower(name)
+	query = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(query), " ", "_"))
+	if query == "" {
+		return noMatch, 0
+	}
+
+	switch {
+	case name == query:
+		return exactMatch, len(name)
+	case slices.Contains(strings.Split(name, "/"), query):
+		return segmentMatch, len(query)
+	case strings.Contains(name, query):
+		return substringMatch, len(query)
+	}
+
+	// the letters of the query in order, the span is from the first letter matched to the last
+	letters := []rune(query)
+	start, next := -1, 0
+	for i, r := range name {
+		if r != letters[next] {
+			continue
+		}
+
+		if start < 0 {
+			start = i
+		}
+
+		next++
+		if next == len(letters) {
+			return subsequenceMatch, i + 1 - start
+		}
+	}
+
+	return noMatch, 0
+}
+
+// fuzzyMatch reports if query matches name, it's what the test selector filters by.
+func fuzzyMatch(name, query string) bool {
+	tier, _ := matchQuery(name, query)
+	return tier != noMatch
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_matchTests(t *testing.T) {
+	tests := []Test{
+		{Name: "TestAlpha"},
+		{Name: "TestAlpha/first_case"},
+		{Name: "TestAlphabet"},
+		{Name: "TestBeta/alpha"},
+		{Name: "TestParseLongInput"},
+	}
+
+	cases := []struct {
+		name     string
+		query    string
+		expected []string
+		best     int
+	}{
+		{
+			name:     "exact name",
+			query:    "testalpha",
+			expected: []string{"TestAlpha", "TestAlpha/first_case", "TestAlphabet", "TestBeta/alpha"},
+			best:     1,
+		},
+		{
+			name:     "subtest name with spaces",
+			query:    "first case",
+			expected: []string{"TestAlpha/first_case"},
+			best:     1,
+		},
+		{
+			name:     "segment beats substring",
+			query:    "alpha",
+			expected: []string{"TestBeta/alpha", "TestAlpha", "TestAlpha/first_case", "TestAlphabet"},
+			best:     1,
+		},
+		{
+			name:     "ambiguous substring",
+			query:    "Alph",
+			expected: []string{"TestAlpha", "TestAlpha/first_case", "TestAlphabet", "TestBeta/alpha"},
+			best:     4,
+		},
+		{
+			name:     "letters in order",
+			query:    "parselong",
+			expected: []string{"TestParseLongInput"},
+			best:     1,
+		},
+		{
+			name:     "tighter letter matches first",
+			query:    "tpi",
+			expected: []string{"TestAlpha/first_case", "TestParseLongInput"},
+			best:     2,
+		},
+		{
+			name:  "no match",
+			query: "gamma",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, best := matchTests(tests, tt.query)
+			assert.Equal(t, tt.expected, testNames(matches))
+			assert.Equal(t, tt.best, best)
+		})
+	}
+}
+
+func Test_fuzzyMatch(t *testing.T) {
+	assert.True(t, fuzzyMatch("TestAlpha/first_case", "alfc"))
+	assert.True(t, fuzzyMatch("TestÄpfel", "äpf"))
+	assert.False(t, fuzzyMatch("TestAlpha", "ahpla"))
+	assert.False(t, fuzzyMatch("TestAlpha", ""))
+}
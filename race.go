@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// raceDelimiter is the line the race detector writes before and after each report.
+const raceDelimiter = "==================\n"
+
+// RaceReport is a data race found during a run, the two conflicting accesses and where their goroutines were
+// created along with the tests that triggered it.
+type RaceReport struct {
+	Package  string
+	Tests    []string
+	Accesses []RaceStack
+	Created  []RaceStack
+}
+
+// RaceStack is one of the stacks of a race report, IE "Read at 0x00c000018348 by goroutine 9".
+type RaceStack struct {
+	Description string
+	Frames      []RaceFrame
+}
+
+// RaceFrame is a single call in a stack.
+type RaceFrame struct {
+	Func string
+	File string
+	Line int
+}
+
+// parseRaceReport parses the lines between the delimiters of a race report.
+func parseRaceReport(lines []string) RaceReport {
+	var report RaceReport
+	var stack *RaceStack
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || trimmed == "WARNING: DATA RACE":
+			continue
+
+		// headers aren't indented, IE "Previous write at 0x00c000018348 by goroutine 8:"
+		case !strings.HasPrefix(line, " ") && strings.HasSuffix(trimmed, ":"):
+			description := strings.TrimSuffix(trimmed, ":")
+			switch {
+			case strings.HasPrefix(description, "Goroutine "):
+				report.Created = append(report.Created, RaceStack{Description: description})
+				stack = &report.Created[len(report.Created)-1]
+			case strings.Contains(description, " by "):
+				report.Accesses = append(report.Accesses, RaceStack{Description: description})
+				stack = &report.Accesses[len(report.Accesses)-1]
+			default:
+				stack = nil
+			}
+
+		case stack == nil:
+			continue
+
+		// the file of a call is on the line after its function, IE "/tmp/e2e/race_test.go:14 +0x33"
+		case strings.HasPrefix(trimmed, "/") || strings.Contains(trimmed, ".go:"):
+			if len(stack.Frames) == 0 {
+				continue
+			}
+
+			location, _, _ := strings.Cut(trimmed, " +0x")
+			i := strings.LastIndex(location, ":")
+			if i < 0 {
+				continue
+			}
+
+			frame := &stack.Frames[len(stack.Frames)-1]
+			frame.File = location[:i]
+			frame.Line, _ = strconv.Atoi(location[i+1:])
+
+		default:
+			stack.Frames = append(stack.Frames, RaceFrame{Func: strings.TrimSuffix(trimmed, "()")})
+		}
+	}
+
+	return report
+}
+
+// Signature identifies the race regardless of addresses, goroutine ids or which of the accesses came first, so
+// the same race found by different tests or runs is only reported once.
+func (r RaceReport) Signature() string {
+	var accesses []string
+	for _, access := range r.Accesses {
+		kind, _, _ := strings.Cut(access.Description, " at ")
+		kind = strings.TrimPrefix(strings.ToLower(kind), "previous ")
+		accesses = append(accesses, kind+" "+access.frame().String())
+	}
+
+	slices.Sort(accesses)
+
+	return r.Package + " " + strings.Join(accesses, " ")
+}
+
+// frame returns the first frame of the stack outside the standard library, IE where the test or the code it
+// covers made the access rather than the map or sync internals it went through.
+func (s RaceStack) frame() RaceFrame {
+	for _, frame := range s.Frames {
+		// standard library import paths don't have a dot in their first element, IE sync/atomic.AddInt32
+		first, _, hasSlash := strings.Cut(frame.Func, "/")
+		if !hasSlash {
+			first, _, _ = strings.Cut(frame.Func, ".")
+		}
+
+		if strings.Contains(first, ".") {
+			return frame
+		}
+	}
+
+	if len(s.Frames) == 0 {
+		return RaceFrame{}
+	}
+
+	return s.Frames[0]
+}
+
+func (f RaceFrame) String() string {
+	return fmt.Sprintf("%s:%d %s", f.File, f.Line, f.Func)
+}
+
+// addRace records a race report written by test, a race already found is attributed to the test as well.
+func (w *testEventWriter) addRace(pkg, test string, lines []string) {
+	report := parseRaceReport(lines)
+	report.Package = pkg
+
+	signature := report.Signature()
+	i := slices.IndexFunc(w.races, func(r RaceReport) bool { return r.Signature() == signature })
+	if i < 0 {
+		w.races = append(w.races, report)
+		i = len(w.races) - 1
+	}
+
+	// races reported outside of a test, IE from TestMain, aren't attributed to one
+	if test != "" && !slices.Contains(w.races[i].Tests, test) {
+		w.races[i].Tests = append(w.races[i].Tests, test)
+	}
+}
+
+// reportRaces prints the races found by the run, marking those no earlier run in the history found.
+func reportRaces(events *testEventWriter) {
+	if len(events.races) == 0 {
+		return
+	}
+
+	known := knownRaces()
+
+	fmt.Fprintln(events.out)
+	fmt.Fprintf(events.out, "Data races: %d\n", len(events.races))
+	for _, race := range events.races {
+		status := "seen before"
+		if !known[race.Signature()] {
+			status = "new"
+		}
+
+		tests := strings.Join(race.Tests, ", ")
+		if tests == "" {
+			tests = "outside of a test"
+		}
+
+		fmt.Fprintln(events.out)
+		fmt.Fprintln(events.out, resultColor("fail", fmt.Sprintf("DATA RACE (%s) in %s %s", status, race.Package, tests)))
+		for _, stack := range slices.Concat(race.Accesses, race.Created) {
+			fmt.Fprintf(events.out, "  %s\n      %s\n", stack.Description, stack.frame())
+		}
+	}
+}
+
+// knownRaces returns the signatures of the races logged with any history entry.
+func knownRaces() map[string]bool {
+	db := getHistoryFile(historyFile)
+	defer db.Close()
+
+	known := map[string]bool{}
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("history"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var he HistoryEntry
+			he.Load(v)
+
+			for _, race := range he.Races {
+				known[race.Signature()] = true
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return known
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// raceLines is a report written by the race detector, between the delimiters.
+var raceLines = []string{
+	"WARNING: DATA RACE\n",
+	"Read at 0x00c000018348 by goroutine 9:\n",
+	"  example.com/e2e.TestRace.func1.1()\n",
+	"      /tmp/e2e/race_test.go:14 +0x33\n",
+	"\n",
+	"Previous write at 0x00c000018348 by goroutine 8:\n",
+	"  sync/atomic.AddInt32()\n",
+	"      /usr/local/go/src/runtime/race_amd64.s:281 +0xb\n",
+	"  example.com/e2e.TestRace.func1()\n",
+	"      /tmp/e2e/race_test.go:15 +0x138\n",
+	"  testing.tRunner()\n",
+	"      /usr/local/go/src/testing/testing.go:2193 +0x21c\n",
+	"\n",
+	"Goroutine 9 (running) created at:\n",
+	"  example.com/e2e.TestRace.func1()\n",
+	"      /tmp/e2e/race_test.go:14 +0x11c\n",
+	"\n",
+	"Goroutine 8 (finished) created at:\n",
+	"  testing.(*T).Run()\n",
+	"      /usr/local/go/src/testing/testing.go:2258 +0xb12\n",
+}
+
+func Test_parseRaceReport(t *testing.T) {
+	report := parseRaceReport(raceLines)
+
+	assert.Equal(t, []RaceStack{
+		{
+			Description: "Read at 0x00c000018348 by goroutine 9",
+			Frames:      []RaceFrame{{Func: "example.com/e2e.TestRace.func1.1", File: "/tmp/e2e/race_test.go", Line: 14}},
+		},
+		{
+			Description: "Previous write at 0x00c000018348 by goroutine 8",
+			Frames: []RaceFrame{
+				{Func: "sync/atomic.AddInt32", File: "/usr/local/go/src/runtime/race_amd64.s", Line: 281},
+				{Func: "example.com/e2e.TestRace.func1", File: "/tmp/e2e/race_test.go", Line: 15},
+				{Func: "testing.tRunner", File: "/usr/local/go/src/testing/testing.go", Line: 2193},
+			},
+		},
+	}, report.Accesses)
+
+	assert.Len(t, report.Created, 2)
+	assert.Equal(t, "Goroutine 9 (running) created at", report.Created[0].Description)
+	assert.Equal(t, RaceFrame{Func: "testing.(*T).Run", File: "/usr/local/go/src/testing/testing.go", Line: 2258}, report.Created[1].frame())
+
+	report.Package = "example.com/e2e"
+	assert.Equal(t, "example.com/e2e read /tmp/e2e/race_test.go:14 example.com/e2e.TestRace.func1.1 "+
+		"write /tmp/e2e/race_test.go:15 example.com/e2e.TestRace.func1", report.Signature())
+}
+
+func Test_testEventWriter_races(t *testing.T) {
+	var stream strings.Builder
+	output := func(test, line string) {
+		stream.WriteString(`{"Action":"output","Package":"example.com/e2e","Test":"` + test + `","Output":"` +
+			strings.ReplaceAll(strings.ReplaceAll(line, "\n", `\n`), "\t", `\t`) + "\"}\n")
+	}
+
+	for _, test := range []string{"TestRace/one", "TestRace/two"} {
+		output(test, "=== RUN   "+test+"\n")
+		output(test, raceDelimiter)
+		for _, line := range raceLines {
+			// the goroutines differ between tests but it's the same race
+			output(test, strings.ReplaceAll(line, "goroutine 9", "goroutine 12"))
+		}
+		output(test, raceDelimiter)
+		output(test, "    testing.go:1865: race detected during execution of test\n")
+	}
+
+	quiet = boolPtr(false)
+
+	var out strings.Builder
+	w := newTestEventWriter(&out)
+	w.Write([]byte(stream.String()))
+	w.Flush()
+
+	assert.Equal(t, "=== RUN   TestRace/one\n    testing.go:1865: race detected during execution of test\n"+
+		"=== RUN   TestRace/two\n    testing.go:1865: race detected during execution of test\n", out.String())
+	assert.Len(t, w.races, 1)
+	assert.Equal(t, []string{"TestRace/one", "TestRace/two"}, w.races[0].Tests)
+}
+
+func Test_reportRaces(t *testing.T) {
+	isolateHistory(t)
+
+	colorize := globalConfig.ColorizeOutput
+	t.Cleanup(func() { globalConfig.ColorizeOutput = colorize })
+	globalConfig.ColorizeOutput = false
+
+	race := parseRaceReport(raceLines)
+	race.Package = "example.com/e2e"
+	race.Tests = []string{"TestRace/one"}
+
+	var out strings.Builder
+	w := newTestEventWriter(&out)
+	w.races = []RaceReport{race}
+
+	reportRaces(w)
+	assert.Contains(t, out.String(), "DATA RACE (new) in example.com/e2e TestRace/one\n"+
+		"  Read at 0x00c000018348 by goroutine 9\n"+
+		"      /tmp/e2e/race_test.go:14 example.com/e2e.TestRace.func1.1\n"+
+		"  Previous write at 0x00c000018348 by goroutine 8\n"+
+		"      /tmp/e2e/race_test.go:15 example.com/e2e.TestRace.func1\n")
+
+	saveHistoryEntry(HistoryEntry{Path: "go", Args: []string{"go", "test", "-race"}, Races: w.races})
+
+	out.Reset()
+	reportRaces(w)
+	assert.Contains(t, out.String(), "DATA RACE (seen before)")
+}
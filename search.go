@@ -1,15 +1,19 @@
 package main
 
 import (
-	"errors"
+	"cmp"
 	"fmt"
 	"go/ast"
-	"go/parser"
 	"go/token"
-	"io/fs"
+	"go/types"
+	"maps"
 	"path/filepath"
 	dbg "runtime/debug"
+	"slices"
 	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
 )
 
 // Test represents a test case and the file it is in.
@@ -17,194 +21,647 @@ type Test struct {
 	File        string
 	Name        string
 	IsBenchmark bool
+	IsFuzz      bool
+	IsExample   bool
 	FilePath    string
 	LineNumber  int
+	// EndLineNumber is the last line in FilePath of what LineNumber points at, the test function, the t.Run call or
+	// the table row.
+	EndLineNumber int
+	// BodyLineNumber is the line in File of the closure passed to t.Run. Table rows share a closure, so this is where
+	// the debugger stops instead of LineNumber which points at the row itself.
+	BodyLineNumber int
+	// BodyEndLineNumber is the last line in File of the closure passed to t.Run.
+	BodyEndLineNumber int
+	// TestingParam is the name of the closure's *testing.T, used to only stop on the selected subtest.
+	TestingParam string
+	// ExpectedOutput is the // Output: comment of an example.
+	ExpectedOutput string
+	// SuiteMethod is the testify suite method the test belongs to, passed to -testify.m.
+	SuiteMethod string
+	// Module is the path of the module that owns the test.
+	Module string
+	// IsWildcard is set when part of the name couldn't be resolved statically, each * in the name matches anything
+	// so the whole family of subtests is run.
+	IsWildcard bool
+	// Learned is set for subtests that weren't found by discovery but were seen in a previous run, LastSeen is when.
+	Learned  bool
+	LastSeen time.Time
 }
 
-func getTestsFromDir(dir string, benchmarks bool) ([]Test, error) {
-	availableTests := []Test{}
+// loadMode is the information go/packages needs to load so subtest tables can be resolved with type information.
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedTypes |
+	packages.NeedTypesInfo | packages.NeedSyntax | packages.NeedModule
 
-	err := filepath.WalkDir(dir, func(path string, info fs.DirEntry, err error) error {
-		if errors.Is(err, fs.ErrNotExist) {
-			return nil
+// testKind is the kind of test function to discover.
+type testKind int
+
+const (
+	kindTest testKind = iota
+	kindBenchmark
+	kindFuzz
+	kindExample
+)
+
+func getTestsFromDir(dir string, kind testKind) ([]Test, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := scanPackageDirs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error walking the path %s: %w", dir, err)
+	}
+
+	// only packages that changed since the last run need to be loaded and type checked
+	found, stale := lookupDiscoveryCache(kind, dirs)
+	if len(stale) > 0 {
+		// each module has to be loaded from its own root
+		byModule := map[string][]string{}
+		for _, pkgDir := range stale {
+			byModule[dirs[pkgDir].ModRoot] = append(byModule[dirs[pkgDir].ModRoot], pkgDir)
 		}
 
-		if info.IsDir() {
-			return nil
+		var pkgs []*testPackage
+		for modRoot, patterns := range byModule {
+			modPkgs, err := loadTestPackages(modRoot, patterns...)
+			if err != nil {
+				return nil, fmt.Errorf("error loading packages in %s: %w", modRoot, err)
+			}
+
+			pkgs = append(pkgs, modPkgs...)
 		}
 
+		discovered := discoverPackages(pkgs, kind)
+		storeDiscoveryCache(kind, stale, dirs, discovered)
+
+		for _, pkgDir := range stale {
+			found[pkgDir] = discovered[pkgDir]
+		}
+	}
+
+	// group the tests by the module that owns them
+	pkgDirs := slices.Collect(maps.Keys(dirs))
+	slices.SortFunc(pkgDirs, func(a, b string) int {
+		return cmp.Or(strings.Compare(dirs[a].ModRoot, dirs[b].ModRoot), strings.Compare(a, b))
+	})
+
+	availableTests := []Test{}
+	for _, pkgDir := range pkgDirs {
+		availableTests = append(availableTests, found[pkgDir]...)
+	}
+
+	return availableTests, nil
+}
+
+// discover returns the tests of the given kind declared in the package's _test.go files.
+func (tp *testPackage) discover(kind testKind) []Test {
+	var tests []Test
+
+	for _, file := range tp.Syntax {
+		path := tp.Fset.Position(file.Package).Filename
+
 		// if the file isn't a _test.go file, skip it
 		if !strings.HasSuffix(path, "_test.go") {
-			return nil
+			continue
+		}
+
+		switch kind {
+		case kindBenchmark:
+			tests = append(tests, findBenchmarks(path)...)
+		case kindFuzz:
+			tests = append(tests, tp.findFuzzTests(file, path)...)
+		case kindExample:
+			tests = append(tests, tp.findExamples(file, path)...)
+		default:
+			tests = append(tests, tp.findTests(file, path)...)
 		}
+	}
 
-		// load in AST of the file and find test functions
-		if benchmarks {
-			testFuncs := findBenchmarks(path)
-			availableTests = append(availableTests, testFuncs...)
-		} else {
-			testFuncs := findTests(path)
-			availableTests = append(availableTests, testFuncs...)
+	// record the module so tests from nested modules can be told apart
+	if tp.Module != nil {
+		for i := range tests {
+			tests[i].Module = tp.Module.Path
 		}
+	}
 
+	return tests
+}
+
+// buildFlags returns the flags that control which files are part of the build, shared by discovery and go test.
+func buildFlags() []string {
+	if globalConfig.BuildTags == "" {
 		return nil
-	})
+	}
+
+	return []string{"-tags=" + globalConfig.BuildTags}
+}
+
+// loadTestPackages loads the packages matching patterns, including their test files, relative to dir.
+// Packages that fail to type check are still returned so that as many tests as possible can be discovered.
+func loadTestPackages(dir string, patterns ...string) ([]*testPackage, error) {
+	cfg := &packages.Config{
+		Mode:       loadMode,
+		Dir:        dir,
+		Tests:      true,
+		BuildFlags: buildFlags(),
+	}
+
+	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
-		return nil, fmt.Errorf("error walking the path %s: %w", dir, err)
+		return nil, err
 	}
 
-	return availableTests, nil
+	var testPkgs []*testPackage
+	for _, pkg := range pkgs {
+		if *verbose {
+			for _, pkgErr := range pkg.Errors {
+				fmt.Println("Error loading", pkg.ID, pkgErr)
+			}
+		}
+
+		// skip the generated test main packages, and any package that couldn't be loaded at all
+		if strings.HasSuffix(pkg.ID, ".test") || pkg.TypesInfo == nil {
+			continue
+		}
+
+		testPkgs = append(testPkgs, newTestPackage(pkg))
+	}
+
+	return testPkgs, nil
+}
+
+// testPackage is a type checked package along with the indexes needed to follow subtest tables back to their
+// declarations.
+type testPackage struct {
+	*packages.Package
+
+	// values maps variables to the expression they were initialized with.
+	values map[types.Object]ast.Expr
+	// ranges maps range keys and values to the range statement declaring them.
+	ranges map[types.Object]*ast.RangeStmt
+	// funcs maps functions declared in the package to their declaration.
+	funcs map[types.Object]*ast.FuncDecl
 }
 
-// findTests loads the AST of a file and returns all test functions in the file.
+func newTestPackage(pkg *packages.Package) *testPackage {
+	tp := &testPackage{
+		Package: pkg,
+		values:  map[types.Object]ast.Expr{},
+		ranges:  map[types.Object]*ast.RangeStmt{},
+		funcs:   map[types.Object]*ast.FuncDecl{},
+	}
+
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.FuncDecl:
+				if obj := pkg.TypesInfo.Defs[x.Name]; obj != nil {
+					tp.funcs[obj] = x
+				}
+
+			case *ast.ValueSpec:
+				if len(x.Names) != len(x.Values) {
+					return true
+				}
+
+				for i, name := range x.Names {
+					tp.addValue(name, x.Values[i])
+				}
+
+			case *ast.AssignStmt:
+				if len(x.Lhs) != len(x.Rhs) {
+					return true
+				}
+
+				for i, lhs := range x.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok {
+						tp.addValue(ident, x.Rhs[i])
+					}
+				}
+
+			case *ast.RangeStmt:
+				for _, e := range []ast.Expr{x.Key, x.Value} {
+					if ident, ok := e.(*ast.Ident); ok {
+						if obj := pkg.TypesInfo.ObjectOf(ident); obj != nil {
+							tp.ranges[obj] = x
+						}
+					}
+				}
+			}
+
+			return true
+		})
+	}
+
+	return tp
+}
+
+// addValue records the first value assigned to ident, later assignments such as appends are ignored.
+func (tp *testPackage) addValue(ident *ast.Ident, value ast.Expr) {
+	obj := tp.TypesInfo.ObjectOf(ident)
+	if obj == nil {
+		return
+	}
+
+	if _, ok := tp.values[obj]; !ok {
+		tp.values[obj] = value
+	}
+}
+
+// findTests loads the package containing path and returns all test functions declared in the file.
 func findTests(path string) []Test {
+	pkg, file := loadTestFile(path)
+	if pkg == nil {
+		return nil
+	}
+
+	return pkg.findTests(file, path)
+}
+
+// loadTestFile loads the package containing path and returns it along with the syntax of the file.
+func loadTestFile(path string) (*testPackage, *ast.File) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		panic(err)
+	}
+
+	pkgs, err := loadTestPackages(filepath.Dir(abs), "file="+abs)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if pkg.Fset.Position(file.Package).Filename == abs {
+				return pkg, file
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// findTests returns all test functions in the file along with the subtests they run.
+func (tp *testPackage) findTests(f *ast.File, path string) []Test {
 	var tests []Test
 
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	namer := newSubtestNamer()
+	for _, decl := range f.Decls {
+		x, ok := decl.(*ast.FuncDecl)
+		if !ok || x.Recv != nil || x.Body == nil {
+			continue
+		}
+
+		if *verbose {
+			fmt.Println("Evalutating", x.Name.Name)
+		}
+
+		// skip non test functions
+		if !strings.HasPrefix(x.Name.Name, "Test") || x.Name.Name == "TestMain" {
+			continue
+		}
+
+		// create root test entry
+		tests = append(tests, Test{
+			File:          path,
+			Name:          x.Name.Name,
+			FilePath:      path,
+			LineNumber:    tp.Fset.Position(x.Pos()).Line,
+			EndLineNumber: tp.Fset.Position(x.End()).Line,
+		})
+
+		tests = append(tests, tp.subtests(namer, path, x.Name.Name, x.Body, nil)...)
+	}
+
+	return tests
+}
+
+// subtestCase is a subtest name along with where the case is defined, either the t.Run call or a table row.
+type subtestCase struct {
+	name string
+	pos  token.Pos
+	end  token.Pos
+	// wildcard is set when part of the name couldn't be resolved and has been replaced with *.
+	wildcard bool
+}
+
+// subtests returns all subtests started with t.Run within body, named as the testing package would name them.
+// When a helper declared in the package is called, its body is inspected with the helper's parameters bound to
+// the call's arguments.
+func (tp *testPackage) subtests(namer *subtestNamer, path, parentTestName string, body ast.Node,
+	scope *helperScope) []Test {
+	var subtests []Test
+
+	// tables and helpers can be declared in another file of the package
+	testFile, err := filepath.Abs(path)
 	if err != nil {
 		panic(err)
 	}
 
-	ast.Inspect(f, func(n ast.Node) bool {
-		// in the event of a panic catch it and print debug information
-		defer func() {
-			if r := recover(); r != nil {
-				if *verbose {
-					fmt.Printf("Error in file %s : %s\n%s", path, r, dbg.Stack())
+	var inspect func(n ast.Node) bool
+	inspect = func(n ast.Node) bool {
+		c, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		if tp.isSuiteRunCall(c) {
+			subtests = append(subtests, tp.suiteTests(namer, path, parentTestName, c)...)
+			return false
+		}
+
+		if !tp.isRunCall(c) {
+			helperBody, helperScope := tp.helperCall(c, scope)
+			if helperBody == nil {
+				return true
+			}
+
+			subtests = append(subtests, tp.subtests(namer, path, parentTestName, helperBody, helperScope)...)
+
+			// arguments bound to the helper's parameters were inspected where the helper uses them, IE a closure
+			// it passes to t.Run, inspecting them here too would list their subtests under the wrong parent
+			for _, arg := range c.Args {
+				if !helperScope.binds(arg) {
+					ast.Inspect(arg, inspect)
 				}
 			}
-		}()
 
-		switch x := n.(type) {
-		case *ast.FuncDecl:
-			if *verbose {
-				fmt.Println("Evalutating", x.Name.Name)
+			return false
+		}
+
+		f, _ := scope.resolve(tp, c.Args[1]).(*ast.FuncLit)
+		for _, sc := range tp.subtestNames(c, scope) {
+			testName, duplicate := namer.name(parentTestName, sc.name)
+			if duplicate && *verbose {
+				fmt.Printf("Duplicate subtest name %q @ %s, selecting it will run %s\n", sc.name, tp.Fset.Position(sc.pos), testName)
 			}
 
-			// skip non test functions
-			if !strings.HasPrefix(x.Name.Name, "Test") || x.Name.Name == "TestMain" {
-				return true
+			subtest := Test{
+				File:          path,
+				Name:          testName,
+				FilePath:      path,
+				LineNumber:    tp.Fset.Position(sc.pos).Line,
+				EndLineNumber: tp.Fset.Position(sc.end).Line,
+				IsWildcard:    sc.wildcard,
 			}
 
-			// create root test entry
-			tests = append(tests, Test{
-				File:       path,
-				Name:       x.Name.Name,
-				FilePath:   path,
-				LineNumber: fset.Position(x.Pos()).Line,
-			})
+			if casePos := tp.Fset.Position(sc.pos); casePos.Filename != testFile {
+				subtest.FilePath = casePos.Filename
+			}
 
-			var subtests []string
-			for _, item := range x.Body.List {
-				subtests = append(subtests, astToTests(x.Name.Name, item)...)
+			if f != nil && tp.Fset.Position(f.Pos()).Filename == testFile {
+				subtest.BodyLineNumber = tp.Fset.Position(f.Pos()).Line
+				subtest.BodyEndLineNumber = tp.Fset.Position(f.End()).Line
+				subtest.TestingParam = funcParamName(f)
 			}
 
-			// convert subtests into Test entries
-			for _, subtest := range subtests {
-				tests = append(tests, Test{
-					File:       path,
-					Name:       subtest,
-					FilePath:   path,
-					LineNumber: fset.Position(x.Pos()).Line,
-				})
+			subtests = append(subtests, subtest)
+
+			// check the closure for nested subtests
+			if f != nil {
+				subtests = append(subtests, tp.subtests(namer, path, testName, f.Body, scope)...)
 			}
 		}
 
+		// the closure has already been inspected for each name
+		return false
+	}
+
+	ast.Inspect(body, inspect)
+
+	return subtests
+}
+
+// isRunCall reports if c is a call to Run on a *testing.T or a testify suite.
+func (tp *testPackage) isRunCall(c *ast.CallExpr) bool {
+	sel, ok := c.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Run" || len(c.Args) != 2 {
+		return false
+	}
+
+	fn, ok := tp.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return false
+	}
+
+	switch fn.Pkg().Path() {
+	case "testing":
 		return true
-	})
+	case testifySuitePkg:
+		// suite.Run(t, s) starts a whole suite, only s.Run is a subtest
+		return fn.Type().(*types.Signature).Recv() != nil
+	}
 
-	return tests
+	return false
+}
+
+// funcParamName returns the name of the first parameter of f.
+func funcParamName(f *ast.FuncLit) string {
+	params := f.Type.Params.List
+	if len(params) == 0 || len(params[0].Names) == 0 {
+		return ""
+	}
+
+	return params[0].Names[0].Name
 }
 
-func astToTests(parentTestName string, item ast.Node) []string {
+// subtestNames resolves the name argument of a t.Run call to one or more subtest cases. Names built from the
+// variables of a range loop are evaluated once for every row of the table being ranged over.
+func (tp *testPackage) subtestNames(c *ast.CallExpr, scope *helperScope) []subtestCase {
+	arg := scope.resolve(tp, c.Args[0])
 	defer func() {
-		// don't fail out the whole ast due to one bad test
+		// don't fail out the whole test due to one bad subtest
 		if r := recover(); r != nil {
 			if *verbose {
-				fmt.Println("Error in astToTests", parentTestName, r)
+				fmt.Printf("Error resolving subtest name @ %s: %s\n%s", tp.Fset.Position(arg.Pos()), r, dbg.Stack())
 			}
 		}
 	}()
 
-	var subtests []string
-	switch stmt := item.(type) {
-	case *ast.ExprStmt:
-		switch c := stmt.X.(type) {
-		case *ast.CallExpr:
-			// check if the call is to t.Run
-			// if it is, add the subtest to the list of tests
-			// if it isn't, continue
-			f, ok := c.Fun.(*ast.SelectorExpr)
-			if !ok {
-				return nil
-			}
-
-			if f.Sel.Name != "Run" {
-				return nil
-			}
-
-			// set args to the first argument of the t.Run call (the subtest name)
-			// if the argument is a basic literal, IE a string add it to the list of subtests else look up the field name
-			var testName string
-			if v, ok := c.Args[0].(*ast.BasicLit); ok {
-				testName = parentTestName + "/" + strings.ReplaceAll(v.Value, "\"", "")
-				// create entry for this test before checking for subtests within closure
-				subtests = append(subtests, testName)
-			} else {
-				// this is a table test we need to find all table entries
-				// if the argument is a composite literal, we have to look up the field name
-				fieldName := c.Args[0].(*ast.SelectorExpr).Sel.Name
-				tableTests := findTestNameInTable(c.Args[0].(*ast.SelectorExpr), fieldName)
-				for _, tableTest := range tableTests {
-					subtests = append(subtests, parentTestName+"/"+tableTest)
-				}
-			}
+	var rows []tableRow
+	rng := tp.findRange(arg, scope, 0)
+	if rng != nil {
+		rows = tp.rangeRows(rng)
+	}
 
-			// check right hand side of assignment for function body
-			if f, ok := c.Args[1].(*ast.FuncLit); ok {
-				for _, fItem := range f.Body.List {
-					subtests = append(subtests, astToTests(testName, fItem)...)
-				}
-			}
+	// the name doesn't depend on a table, or the table couldn't be found and any loop variables become wildcards
+	if len(rows) == 0 {
+		// names passed into a helper point at the call site rather than the helper
+		var at ast.Node = c
+		if arg != c.Args[0] {
+			at = arg
+		}
+
+		if sc, ok := tp.subtestCase(arg, scope, nil, at.Pos(), at.End()); ok {
+			return []subtestCase{sc}
+		}
+
+		return nil
+	}
+
+	var subtests []subtestCase
+	wildcards := map[string]bool{}
+	for _, row := range rows {
+		sc, ok := tp.subtestCase(arg, scope, &rowBinding{rng: rng, row: row}, row.pos, row.end)
+		if !ok {
+			continue
 		}
 
-	// found range statement, check for t.Run calls in the body for subtests
-	case *ast.RangeStmt:
-		// check body for call to t.Run
-		for _, forItem := range stmt.Body.List {
-			subtests = append(subtests, astToTests(parentTestName, forItem)...)
+		// a wildcard matches every row that produced it so only list it once
+		if sc.wildcard {
+			if wildcards[sc.name] {
+				continue
+			}
+
+			wildcards[sc.name] = true
 		}
+
+		subtests = append(subtests, sc)
 	}
 
 	return subtests
 }
 
-func findTestNameInTable(stmt *ast.SelectorExpr, fieldName string) []string {
-	var subtests []string
+// rangeOf returns the range statement obj was declared by and the range variable itself, following copies like
+// tt := tt.
+func (tp *testPackage) rangeOf(obj types.Object) (*ast.RangeStmt, types.Object) {
+	for i := 0; obj != nil && i < maxResolveDepth; i++ {
+		if rng, ok := tp.ranges[obj]; ok {
+			return rng, obj
+		}
 
-	testData := stmt.X.(*ast.Ident).Obj.Decl.(*ast.AssignStmt).Rhs[0]
-	testData = testData.(*ast.UnaryExpr).X.(*ast.Ident).Obj.Decl.(*ast.AssignStmt).Rhs[0]
+		ident, ok := tp.values[obj].(*ast.Ident)
+		if !ok {
+			return nil, nil
+		}
 
-	for _, el := range testData.(*ast.CompositeLit).Elts {
-		lit := el.(*ast.CompositeLit)
-		for _, structField := range lit.Elts {
-			switch t := structField.(type) {
-			case *ast.BasicLit:
-				// this struct isn't keyed so we have to look up parent struct and compare the field name
-				subtests = append(subtests, strings.Trim(t.Value, `"`))
-			case *ast.KeyValueExpr:
-				if t.Key.(*ast.Ident).Name == fieldName {
-					subtests = append(subtests, strings.Trim(t.Value.(*ast.BasicLit).Value, `"`))
-				}
-			case *ast.FuncLit:
-			// if the subtest is a function literal, we can't get the name from the AST
-			case *ast.CompositeLit:
-			default:
-				panic(fmt.Sprintf("Unknown type %T @ %d", t, structField.Pos()))
+		obj = tp.TypesInfo.ObjectOf(ident)
+	}
+
+	return nil, nil
+}
+
+// isRangeKey reports if obj is the key variable of rng.
+func (tp *testPackage) isRangeKey(rng *ast.RangeStmt, obj types.Object) bool {
+	key, ok := rng.Key.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	return tp.TypesInfo.ObjectOf(key) == obj
+}
+
+// maxResolveDepth limits how many variables and function calls are followed when resolving a table.
+const maxResolveDepth = 10
+
+// resolveComposite follows variables, pointers, conversions and function calls within the package back to the
+// composite literal that produced expr.
+func (tp *testPackage) resolveComposite(expr ast.Expr, depth int) *ast.CompositeLit {
+	if depth > maxResolveDepth {
+		return nil
+	}
+
+	switch x := expr.(type) {
+	case *ast.CompositeLit:
+		return x
+	case *ast.ParenExpr:
+		return tp.resolveComposite(x.X, depth+1)
+	case *ast.UnaryExpr:
+		return tp.resolveComposite(x.X, depth+1)
+	case *ast.KeyValueExpr:
+		return tp.resolveComposite(x.Value, depth+1)
+	case *ast.Ident:
+		value, ok := tp.values[tp.TypesInfo.ObjectOf(x)]
+		if !ok {
+			return nil
+		}
+
+		return tp.resolveComposite(value, depth+1)
+	case *ast.CallExpr:
+		// conversions to named slice types
+		if tv, ok := tp.TypesInfo.Types[x.Fun]; ok && tv.IsType() && len(x.Args) == 1 {
+			return tp.resolveComposite(x.Args[0], depth+1)
+		}
+
+		return tp.resolveReturn(x, depth)
+	}
+
+	return nil
+}
+
+// resolveReturn finds the table returned by a helper function declared in the package.
+func (tp *testPackage) resolveReturn(c *ast.CallExpr, depth int) *ast.CompositeLit {
+	var fnIdent *ast.Ident
+	switch fn := c.Fun.(type) {
+	case *ast.Ident:
+		fnIdent = fn
+	case *ast.SelectorExpr:
+		fnIdent = fn.Sel
+	default:
+		return nil
+	}
+
+	decl, ok := tp.funcs[tp.TypesInfo.ObjectOf(fnIdent)]
+	if !ok || decl.Body == nil {
+		return nil
+	}
+
+	var lit *ast.CompositeLit
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncLit:
+			// returns within closures don't belong to this function
+			return false
+		case *ast.ReturnStmt:
+			if lit == nil && len(x.Results) == 1 {
+				lit = tp.resolveComposite(x.Results[0], depth+1)
+			}
+		}
+
+		return lit == nil
+	})
+
+	return lit
+}
+
+// fieldValue returns the expression assigned to fieldName within a struct literal.
+func (tp *testPackage) fieldValue(row *ast.CompositeLit, fieldName string) ast.Expr {
+	for i, el := range row.Elts {
+		switch t := el.(type) {
+		case *ast.KeyValueExpr:
+			if key, ok := t.Key.(*ast.Ident); ok && key.Name == fieldName {
+				return t.Value
+			}
+		default:
+			// this struct isn't keyed so we have to look up the field index in the struct type
+			st := tp.structOf(row)
+			if st == nil {
+				continue
+			}
+
+			if i < st.NumFields() && st.Field(i).Name() == fieldName {
+				return t
 			}
 		}
 	}
 
-	return subtests
+	return nil
+}
+
+// structOf returns the struct type of a composite literal, elided types are resolved by the type checker.
+func (tp *testPackage) structOf(lit *ast.CompositeLit) *types.Struct {
+	typ := tp.TypesInfo.TypeOf(lit)
+	if typ == nil {
+		return nil
+	}
+
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+
+	st, _ := typ.Underlying().(*types.Struct)
+
+	return st
 }
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_findTests(t *testing.T) {
+	// tables declared in another file are reported with the absolute path of that file
+	casesFile, err := filepath.Abs("testdata/package_table_cases.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	tests := []struct {
 		file  string
 		tests []Test
@@ -14,17 +21,91 @@ func Test_findTests(t *testing.T) {
 		{
 			file: "testdata/nested_t_run_string.go",
 			tests: []Test{
-				{Name: "Test_Nested", File: "testdata/nested_t_run_string.go", FilePath: "testdata/nested_t_run_string.go", LineNumber: 7},
-				{Name: "Test_Nested/L1", File: "testdata/nested_t_run_string.go", FilePath: "testdata/nested_t_run_string.go", LineNumber: 7},
-				{Name: "Test_Nested/L1/L2", File: "testdata/nested_t_run_string.go", FilePath: "testdata/nested_t_run_string.go", LineNumber: 7},
+				{Name: "Test_Nested", File: "testdata/nested_t_run_string.go", FilePath: "testdata/nested_t_run_string.go", LineNumber: 7, EndLineNumber: 12},
+				{Name: "Test_Nested/L1", File: "testdata/nested_t_run_string.go", FilePath: "testdata/nested_t_run_string.go", LineNumber: 8, EndLineNumber: 11, BodyLineNumber: 8, BodyEndLineNumber: 11, TestingParam: "t"},
+				{Name: "Test_Nested/L1/L2", File: "testdata/nested_t_run_string.go", FilePath: "testdata/nested_t_run_string.go", LineNumber: 9, EndLineNumber: 10, BodyLineNumber: 9, BodyEndLineNumber: 10, TestingParam: "t"},
 			},
 		},
 		{
 			file: "testdata/t_run_for_loop.go",
 			tests: []Test{
-				{Name: "Test_ForLoop", File: "testdata/t_run_for_loop.go", FilePath: "testdata/t_run_for_loop.go", LineNumber: 5},
-				{Name: "Test_ForLoop/test1", File: "testdata/t_run_for_loop.go", FilePath: "testdata/t_run_for_loop.go", LineNumber: 5},
-				{Name: "Test_ForLoop/test2", File: "testdata/t_run_for_loop.go", FilePath: "testdata/t_run_for_loop.go", LineNumber: 5},
+				{Name: "Test_ForLoop", File: "testdata/t_run_for_loop.go", FilePath: "testdata/t_run_for_loop.go", LineNumber: 5, EndLineNumber: 19},
+				{Name: "Test_ForLoop/test1", File: "testdata/t_run_for_loop.go", FilePath: "testdata/t_run_for_loop.go", LineNumber: 9, EndLineNumber: 9, BodyLineNumber: 14, BodyEndLineNumber: 17, TestingParam: "t"},
+				{Name: "Test_ForLoop/test2", File: "testdata/t_run_for_loop.go", FilePath: "testdata/t_run_for_loop.go", LineNumber: 10, EndLineNumber: 10, BodyLineNumber: 14, BodyEndLineNumber: 17, TestingParam: "t"},
+			},
+		},
+		{
+			file: "testdata/package_table.go",
+			tests: []Test{
+				{Name: "Test_PackageTable", File: "testdata/package_table.go", FilePath: "testdata/package_table.go", LineNumber: 12, EndLineNumber: 16},
+				{Name: "Test_PackageTable/shared1", File: "testdata/package_table.go", FilePath: "testdata/package_table.go", LineNumber: 8, EndLineNumber: 8, BodyLineNumber: 14, BodyEndLineNumber: 14, TestingParam: "t"},
+				{Name: "Test_PackageTable/shared2", File: "testdata/package_table.go", FilePath: "testdata/package_table.go", LineNumber: 9, EndLineNumber: 9, BodyLineNumber: 14, BodyEndLineNumber: 14, TestingParam: "t"},
+				{Name: "Test_OtherFileTable", File: "testdata/package_table.go", FilePath: "testdata/package_table.go", LineNumber: 18, EndLineNumber: 23},
+				{Name: "Test_OtherFileTable/other1", File: "testdata/package_table.go", FilePath: casesFile, LineNumber: 14, EndLineNumber: 14, BodyLineNumber: 21, BodyEndLineNumber: 21, TestingParam: "t"},
+				{Name: "Test_OtherFileTable/other2", File: "testdata/package_table.go", FilePath: casesFile, LineNumber: 15, EndLineNumber: 15, BodyLineNumber: 21, BodyEndLineNumber: 21, TestingParam: "t"},
+				{Name: "Test_HelperTable", File: "testdata/package_table.go", FilePath: "testdata/package_table.go", LineNumber: 25, EndLineNumber: 29},
+				{Name: "Test_HelperTable/helper1", File: "testdata/package_table.go", FilePath: casesFile, LineNumber: 20, EndLineNumber: 20, BodyLineNumber: 27, BodyEndLineNumber: 27, TestingParam: "t"},
+				{Name: "Test_HelperTable/helper2", File: "testdata/package_table.go", FilePath: casesFile, LineNumber: 21, EndLineNumber: 21, BodyLineNumber: 27, BodyEndLineNumber: 27, TestingParam: "t"},
+				{Name: "Test_NamedSliceTable", File: "testdata/package_table.go", FilePath: "testdata/package_table.go", LineNumber: 31, EndLineNumber: 40},
+				{Name: "Test_NamedSliceTable/named1", File: "testdata/package_table.go", FilePath: "testdata/package_table.go", LineNumber: 33, EndLineNumber: 33, BodyLineNumber: 38, BodyEndLineNumber: 38, TestingParam: "t"},
+				{Name: "Test_NamedSliceTable/named2", File: "testdata/package_table.go", FilePath: "testdata/package_table.go", LineNumber: 34, EndLineNumber: 34, BodyLineNumber: 38, BodyEndLineNumber: 38, TestingParam: "t"},
+			},
+		},
+		{
+			file: "testdata/map_table.go",
+			tests: []Test{
+				{Name: "Test_MapTable", File: "testdata/map_table.go", FilePath: "testdata/map_table.go", LineNumber: 7, EndLineNumber: 20},
+				{Name: "Test_MapTable/literal", File: "testdata/map_table.go", FilePath: "testdata/map_table.go", LineNumber: 11, EndLineNumber: 11, BodyLineNumber: 16, BodyEndLineNumber: 18, TestingParam: "t"},
+				{Name: "Test_MapTable/constant", File: "testdata/map_table.go", FilePath: "testdata/map_table.go", LineNumber: 12, EndLineNumber: 12, BodyLineNumber: 16, BodyEndLineNumber: 18, TestingParam: "t"},
+				{Name: "Test_MapTableInline", File: "testdata/map_table.go", FilePath: "testdata/map_table.go", LineNumber: 22, EndLineNumber: 27},
+				{Name: "Test_MapTableInline/one", File: "testdata/map_table.go", FilePath: "testdata/map_table.go", LineNumber: 23, EndLineNumber: 23, BodyLineNumber: 25, BodyEndLineNumber: 25, TestingParam: "t"},
+				{Name: "Test_MapTableInline/two", File: "testdata/map_table.go", FilePath: "testdata/map_table.go", LineNumber: 23, EndLineNumber: 23, BodyLineNumber: 25, BodyEndLineNumber: 25, TestingParam: "t"},
+			},
+		},
+		{
+			file: "testdata/duplicate_names.go",
+			tests: []Test{
+				{Name: "Test_DuplicateNames", File: "testdata/duplicate_names.go", FilePath: "testdata/duplicate_names.go", LineNumber: 5, EndLineNumber: 9},
+				{Name: "Test_DuplicateNames/with_space", File: "testdata/duplicate_names.go", FilePath: "testdata/duplicate_names.go", LineNumber: 6, EndLineNumber: 6, BodyLineNumber: 6, BodyEndLineNumber: 6, TestingParam: "t"},
+				{Name: "Test_DuplicateNames/dup", File: "testdata/duplicate_names.go", FilePath: "testdata/duplicate_names.go", LineNumber: 7, EndLineNumber: 7, BodyLineNumber: 7, BodyEndLineNumber: 7, TestingParam: "t"},
+				{Name: "Test_DuplicateNames/dup#01", File: "testdata/duplicate_names.go", FilePath: "testdata/duplicate_names.go", LineNumber: 8, EndLineNumber: 8, BodyLineNumber: 8, BodyEndLineNumber: 8, TestingParam: "t"},
+			},
+		},
+		{
+			file: "testdata/testify_suite.go",
+			tests: []Test{
+				{Name: "TestExampleSuite", File: "testdata/testify_suite.go", FilePath: "testdata/testify_suite.go", LineNumber: 21, EndLineNumber: 23},
+				{Name: "TestExampleSuite/TestFirst", File: "testdata/testify_suite.go", FilePath: "testdata/testify_suite.go", LineNumber: 13, EndLineNumber: 15, SuiteMethod: "TestFirst"},
+				{Name: "TestExampleSuite/TestFirst/nested", File: "testdata/testify_suite.go", FilePath: "testdata/testify_suite.go", LineNumber: 14, EndLineNumber: 14, BodyLineNumber: 14, BodyEndLineNumber: 14, SuiteMethod: "TestFirst"},
+				{Name: "TestExampleSuite/TestSecond", File: "testdata/testify_suite.go", FilePath: "testdata/testify_suite.go", LineNumber: 17, EndLineNumber: 17, SuiteMethod: "TestSecond"},
+			},
+		},
+		{
+			file: "testdata/helper_subtests.go",
+			tests: []Test{
+				{Name: "Test_Helpers", File: "testdata/helper_subtests.go", FilePath: "testdata/helper_subtests.go", LineNumber: 13, EndLineNumber: 22},
+				{Name: "Test_Helpers/direct", File: "testdata/helper_subtests.go", FilePath: "testdata/helper_subtests.go", LineNumber: 14, EndLineNumber: 14, BodyLineNumber: 14, BodyEndLineNumber: 14, TestingParam: "t"},
+				{Name: "Test_Helpers/nested", File: "testdata/helper_subtests.go", FilePath: "testdata/helper_subtests.go", LineNumber: 15, EndLineNumber: 15, BodyLineNumber: 10, BodyEndLineNumber: 10, TestingParam: "t"},
+				{Name: "Test_Helpers/closure", File: "testdata/helper_subtests.go", FilePath: "testdata/helper_subtests.go", LineNumber: 21, EndLineNumber: 21, BodyLineNumber: 18, BodyEndLineNumber: 18, TestingParam: "t"},
+				// closures passed to a helper are only listed under the subtest the helper runs them in
+				{Name: "Test_HelperClosures", File: "testdata/helper_subtests.go", FilePath: "testdata/helper_subtests.go", LineNumber: 28, EndLineNumber: 36},
+				{Name: "Test_HelperClosures/outer", File: "testdata/helper_subtests.go", FilePath: "testdata/helper_subtests.go", LineNumber: 29, EndLineNumber: 29, BodyLineNumber: 29, BodyEndLineNumber: 31, TestingParam: "t"},
+				{Name: "Test_HelperClosures/outer/inner", File: "testdata/helper_subtests.go", FilePath: "testdata/helper_subtests.go", LineNumber: 30, EndLineNumber: 30, BodyLineNumber: 30, BodyEndLineNumber: 30, TestingParam: "t"},
+				{Name: "Test_HelperClosures/called", File: "testdata/helper_subtests.go", FilePath: "testdata/helper_subtests.go", LineNumber: 34, EndLineNumber: 34, BodyLineNumber: 34, BodyEndLineNumber: 34, TestingParam: "t"},
+			},
+		},
+		{
+			file: "testdata/computed_names.go",
+			tests: []Test{
+				{Name: "Test_ComputedNames", File: "testdata/computed_names.go", FilePath: "testdata/computed_names.go", LineNumber: 12, EndLineNumber: 34},
+				{Name: "Test_ComputedNames/const-name", File: "testdata/computed_names.go", FilePath: "testdata/computed_names.go", LineNumber: 13, EndLineNumber: 13, BodyLineNumber: 13, BodyEndLineNumber: 13, TestingParam: "t"},
+				{Name: "Test_ComputedNames/case-0", File: "testdata/computed_names.go", FilePath: "testdata/computed_names.go", LineNumber: 18, EndLineNumber: 18, BodyLineNumber: 23, BodyEndLineNumber: 23, TestingParam: "t"},
+				{Name: "Test_ComputedNames/case-1", File: "testdata/computed_names.go", FilePath: "testdata/computed_names.go", LineNumber: 19, EndLineNumber: 19, BodyLineNumber: 23, BodyEndLineNumber: 23, TestingParam: "t"},
+				{Name: "Test_ComputedNames/in=1", File: "testdata/computed_names.go", FilePath: "testdata/computed_names.go", LineNumber: 18, EndLineNumber: 18, BodyLineNumber: 26, BodyEndLineNumber: 26, TestingParam: "t"},
+				{Name: "Test_ComputedNames/in=2", File: "testdata/computed_names.go", FilePath: "testdata/computed_names.go", LineNumber: 19, EndLineNumber: 19, BodyLineNumber: 26, BodyEndLineNumber: 26, TestingParam: "t"},
+				{Name: "Test_ComputedNames/n0", File: "testdata/computed_names.go", FilePath: "testdata/computed_names.go", LineNumber: 29, EndLineNumber: 31, BodyLineNumber: 30, BodyEndLineNumber: 30, TestingParam: "t"},
+				{Name: "Test_ComputedNames/n1", File: "testdata/computed_names.go", FilePath: "testdata/computed_names.go", LineNumber: 29, EndLineNumber: 31, BodyLineNumber: 30, BodyEndLineNumber: 30, TestingParam: "t"},
+				{Name: "Test_ComputedNames/rand-*", File: "testdata/computed_names.go", FilePath: "testdata/computed_names.go", LineNumber: 33, EndLineNumber: 33, BodyLineNumber: 33, BodyEndLineNumber: 33, TestingParam: "t", IsWildcard: true},
 			},
 		},
 	}
@@ -42,7 +123,7 @@ func Test_findTests(t *testing.T) {
 
 func Benchmark_findTests(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		tests, err := getTestsFromDir("/Users/dlaird/projects/docuverse-server/", true)
+		tests, err := getTestsFromDir("/Users/dlaird/projects/docuverse-server/", kindBenchmark)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -53,3 +134,59 @@ func Benchmark_findTests(b *testing.B) {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func Test_findFuzzTests(t *testing.T) {
+	corpus, err := filepath.Abs("testdata/testdata/fuzz/Fuzz_Reverse/582528ddfad69eb5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := filepath.Abs("testdata/fuzz_target.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, file := loadTestFile(path)
+	if pkg == nil {
+		t.Fatal("expected package to load")
+	}
+
+	expected := []Test{
+		{Name: "Fuzz_Reverse", IsFuzz: true, File: path, FilePath: path, LineNumber: 5},
+		{Name: "Fuzz_Reverse/seed#0", IsFuzz: true, File: path, FilePath: path, LineNumber: 6},
+		{Name: "Fuzz_Reverse/seed#1", IsFuzz: true, File: path, FilePath: path, LineNumber: 7},
+		{Name: "Fuzz_Reverse/582528ddfad69eb5", IsFuzz: true, File: path, FilePath: corpus, LineNumber: 1},
+	}
+
+	assert.Equal(t, expected, pkg.findFuzzTests(file, path))
+}
+
+func Test_getTestsFromDirModules(t *testing.T) {
+	isolateHistory(t)
+
+	dir := t.TempDir()
+	testFile := func(name string) string {
+		return "package pkg\n\nimport \"testing\"\n\nfunc " + name + "(t *testing.T) {}\n"
+	}
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/root\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(dir, "a_test.go"), testFile("TestRoot"))
+	writeFile(t, filepath.Join(dir, "never_test.go"), "//go:build never\n\n"+testFile("TestNever"))
+	writeFile(t, filepath.Join(dir, "testdata", "fixture_test.go"), testFile("TestFixture"))
+	writeFile(t, filepath.Join(dir, "vendor", "dep", "dep_test.go"), testFile("TestVendor"))
+	writeFile(t, filepath.Join(dir, ".hidden", "hidden_test.go"), testFile("TestHidden"))
+	writeFile(t, filepath.Join(dir, "nested", "go.mod"), "module example.com/nested\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(dir, "nested", "b_test.go"), testFile("TestNested"))
+
+	tests, err := getTestsFromDir(dir, kindTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var modules []string
+	for _, test := range tests {
+		modules = append(modules, test.Name+"@"+test.Module)
+	}
+
+	assert.Equal(t, []string{"TestRoot@example.com/root", "TestNested@example.com/nested"}, modules)
+}
@@ -0,0 +1,607 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC error codes, see https://www.jsonrpc.org/specification#error_object.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	// rpcServerError is returned for requests that fail or can't be done right now, IE a run while one is in
+	// progress.
+	rpcServerError = -32000
+)
+
+// rpcRequest is a request or, without an ID, a notification sent by the client.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// serverMethods are the methods a client can call, each gets the raw params of the request.
+var serverMethods = map[string]func(s *server, params json.RawMessage) (any, error){
+	"listTests":  (*server).listTests,
+	"runTests":   (*server).runTests,
+	"debugTest":  (*server).debugTest,
+	"cancelRun":  (*server).cancelRun,
+	"getHistory": (*server).getHistory,
+	"rerunLast":  (*server).rerunLast,
+}
+
+// server answers the JSON-RPC requests of an editor. Runs are cancelled through stopRun, so only one run, of tests or
+// the debugger, can be in progress at a time.
+type server struct {
+	out     io.Writer
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	lastRun int
+	run     *serverRun
+}
+
+// serverRun is a run in progress, closing stop stops it.
+type serverRun struct {
+	ID   int
+	stop chan struct{}
+}
+
+// cancelled reports if the run was stopped by cancelRun.
+func (r *serverRun) cancelled() bool {
+	select {
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func newServer(out io.Writer) *server {
+	return &server{out: out}
+}
+
+// serveStdio serves requests from stdin until it's closed, the responses and notifications are written to stdout.
+// Messages are framed like the language server protocol so editors can use their usual JSON-RPC clients.
+func serveStdio() error {
+	// anything the runs print goes to stderr so it can't end up in the middle of a message
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	s := newServer(out)
+	testEventListener = s.streamEvent
+
+	return s.serve(os.Stdin)
+}
+
+// serve handles each request read from in as it comes, so a run can be cancelled while it's in progress. When in is
+// closed the run in progress is stopped and serve returns once every request has been answered.
+func (s *server) serve(in io.Reader) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	r := bufio.NewReader(in)
+	for {
+		body, err := readMessage(r)
+		if errors.Is(err, io.EOF) {
+			s.stop()
+			return nil
+		}
+
+		if err != nil {
+			s.stop()
+			return fmt.Errorf("error reading request: %w", err)
+		}
+
+		var req rpcRequest
+		err = json.Unmarshal(body, &req)
+		if err != nil {
+			s.write(rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(req)
+		}()
+	}
+}
+
+// readMessage reads the body of the next message, the headers before it are terminated by an empty line and give
+// its length, IE "Content-Length: 42\r\n\r\n".
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			// the client closing the stream between messages is how it's done
+			if err == io.EOF && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+
+			return nil, fmt.Errorf("error reading headers: %w", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(name, "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q: %w", value, err)
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, errors.New("message without a Content-Length header")
+	}
+
+	body := make([]byte, length)
+	_, err := io.ReadFull(r, body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading message: %w", err)
+	}
+
+	return body, nil
+}
+
+// write sends msg to the client, messages are written whole even when several goroutines send them.
+func (s *server) write(msg any) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	// there's no one left to tell when the client is gone
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(data))
+	s.out.Write(data)
+}
+
+func (s *server) notify(method string, params any) {
+	s.write(rpcNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// handle calls the method of req and answers with its result, notifications from the client aren't answered.
+func (s *server) handle(req rpcRequest) {
+	var result any
+	var err error
+	if method, ok := serverMethods[req.Method]; ok {
+		result, err = s.call(method, req.Params)
+	} else {
+		err = &rpcError{Code: rpcMethodNotFound, Message: "unknown method " + req.Method}
+	}
+
+	if req.ID == nil {
+		return
+	}
+
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: rpcServerError, Message: err.Error()}
+		}
+
+		resp.Result, resp.Error = nil, rpcErr
+	}
+
+	s.write(resp)
+}
+
+// call calls method, the panics used for unexpected errors elsewhere are returned as errors so one bad request
+// doesn't take the server down.
+func (s *server) call(method func(*server, json.RawMessage) (any, error), params json.RawMessage) (result any,
+	err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &rpcError{Code: rpcInternalError, Message: fmt.Sprint(r)}
+		}
+	}()
+
+	return method(s, params)
+}
+
+// decodeParams decodes the params of a request into v, requests without params leave v as is.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+
+	err := json.Unmarshal(params, v)
+	if err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	return nil
+}
+
+// listTestsParams are the params of listTests.
+type listTestsParams struct {
+	// Dir is searched for tests along with the directories below it, the working directory when empty.
+	Dir string
+	// Kind is one of test, benchmark, fuzz or example, test when empty.
+	Kind string
+}
+
+var testKinds = map[string]testKind{
+	"":          kindTest,
+	"test":      kindTest,
+	"benchmark": kindBenchmark,
+	"fuzz":      kindFuzz,
+	"example":   kindExample,
+}
+
+// listTests returns the tests found in a directory, subtests seen in earlier runs are included like with -s.
+func (s *server) listTests(params json.RawMessage) (any, error) {
+	p := listTestsParams{Dir: "."}
+	err := decodeParams(params, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, ok := testKinds[p.Kind]
+	if !ok {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown kind %q", p.Kind)}
+	}
+
+	tests, err := getTestsFromDir(p.Dir, kind)
+	if err != nil {
+		return nil, fmt.Errorf("error getting tests: %w", err)
+	}
+
+	if kind == kindTest {
+		tests = mergeLearnedTests(tests)
+	}
+
+	if tests == nil {
+		tests = []Test{}
+	}
+
+	return struct{ Tests []Test }{tests}, nil
+}
+
+// runStarted is sent once a run is underway, its RunID is what cancelRun takes.
+type runStarted struct {
+	RunID int
+	// Address is where dlv's server listens for a debug session, IE 127.0.0.1:40215.
+	Address string `json:",omitempty"`
+	// Breakpoint is where gotest's own debug sessions stop for the test, with the condition that only stops there
+	// for the selected table row.
+	Breakpoint *serverBreakpoint `json:",omitempty"`
+}
+
+type serverBreakpoint struct {
+	File      string
+	Line      int
+	Condition string `json:",omitempty"`
+}
+
+// runResult is the result of runTests, debugTest and rerunLast.
+type runResult struct {
+	RunID     int
+	Pass      bool
+	Cancelled bool
+}
+
+// startRun runs run as the server's run in progress and returns its result once it's done. The events of the tests
+// are streamed to the client while it runs, see streamEvent.
+func (s *server) startRun(run func(r *serverRun) (bool, error)) (any, error) {
+	s.mu.Lock()
+	if s.run != nil {
+		id := s.run.ID
+		s.mu.Unlock()
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("run %d is still in progress, cancel it first", id)}
+	}
+
+	s.lastRun++
+	r := &serverRun{ID: s.lastRun, stop: make(chan struct{})}
+	s.run = r
+	stopRun = r.stop
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.run = nil
+		stopRun = nil
+		s.mu.Unlock()
+	}()
+
+	pass, err := run(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return runResult{RunID: r.ID, Pass: pass, Cancelled: r.cancelled()}, nil
+}
+
+// stop stops the run in progress, if any.
+func (s *server) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.run != nil && !s.run.cancelled() {
+		close(s.run.stop)
+	}
+}
+
+// streamEvent sends an event of the tests being run to the client.
+func (s *server) streamEvent(ev testEvent) {
+	s.mu.Lock()
+	var id int
+	if s.run != nil {
+		id = s.run.ID
+	}
+	s.mu.Unlock()
+
+	s.notify("testEvent", struct {
+		RunID int
+		Event testEvent
+	}{id, ev})
+}
+
+// runTestsParams are the params of runTests, the tests are those returned by listTests.
+type runTestsParams struct {
+	Tests []Test
+}
+
+// runTests runs the tests like gotest -s would, one go test command per package, and logs the run in the history.
+func (s *server) runTests(params json.RawMessage) (any, error) {
+	var p runTestsParams
+	err := decodeParams(params, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.Tests) == 0 {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "no tests to run"}
+	}
+
+	return s.startRun(func(r *serverRun) (bool, error) {
+		s.notify("runStarted", runStarted{RunID: r.ID})
+
+		if len(p.Tests) == 1 {
+			cmd, outcome := executeTests(p.Tests[0])
+			logRunHistory(cmd, outcome)
+			return outcome.Pass, nil
+		}
+
+		cmds, outcome, err := executeBatch(p.Tests)
+		if err != nil {
+			return false, err
+		}
+
+		logBatchHistory(cmds, outcome)
+
+		return outcome.Pass, nil
+	})
+}
+
+// debugTestParams are the params of debugTest.
+type debugTestParams struct {
+	Test Test
+}
+
+// debugTest starts dlv's server for the test and sends its address with runStarted, the editor connects to it and
+// sets the breakpoints itself. It returns once dlv exits, cancelRun stops it.
+func (s *server) debugTest(params json.RawMessage) (any, error) {
+	var p debugTestParams
+	err := decodeParams(params, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Test.File == "" {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "no test to debug"}
+	}
+
+	return s.startRun(func(r *serverRun) (bool, error) {
+		path, modRoot := testToPathAndRoot(p.Test)
+		cmd := debugCommand(p.Test, path, modRoot, "--headless", "--listen=127.0.0.1:0", "--api-version=2",
+			"--accept-multiclient")
+		cmd.Stderr = os.Stderr
+		setProcessGroup(&cmd)
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return false, err
+		}
+
+		fmt.Println("Running test with debugger:", cmd.Args)
+
+		err = cmd.Start()
+		if err != nil {
+			return false, fmt.Errorf("error starting dlv: %w", err)
+		}
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-r.stop:
+				killProcessGroup(&cmd)
+			case <-done:
+			}
+		}()
+
+		file, line, cond := breakpoint(p.Test)
+		file, _ = filepath.Abs(file)
+
+		// dlv prints where it listens once the test is built, IE "API server listening at: 127.0.0.1:40215", the
+		// rest of its output is the test's
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			fmt.Println(scanner.Text())
+
+			if address, ok := strings.CutPrefix(scanner.Text(), "API server listening at: "); ok {
+				s.notify("runStarted", runStarted{
+					RunID:      r.ID,
+					Address:    address,
+					Breakpoint: &serverBreakpoint{File: file, Line: line, Condition: cond},
+				})
+			}
+		}
+
+		err = cmd.Wait()
+		var exit *exec.ExitError
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.As(err, &exit):
+			return false, nil
+		default:
+			return false, err
+		}
+	})
+}
+
+// cancelRunParams are the params of cancelRun.
+type cancelRunParams struct {
+	RunID int
+}
+
+// cancelRun stops a run in progress, the run's own request is answered with Cancelled set. Cancelled is false when
+// the run isn't in progress, IE it's already done.
+func (s *server) cancelRun(params json.RawMessage) (any, error) {
+	var p cancelRunParams
+	err := decodeParams(params, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cancelled := s.run != nil && s.run.ID == p.RunID && !s.run.cancelled()
+	if cancelled {
+		close(s.run.stop)
+	}
+
+	return struct{ Cancelled bool }{cancelled}, nil
+}
+
+// getHistoryParams are the params of getHistory.
+type getHistoryParams struct {
+	// Dir limits the entries to runs in the module containing it.
+	Dir string
+	// Limit is the most entries returned, all of them when zero.
+	Limit int
+}
+
+// getHistory returns the entries of the history, the most recent first.
+func (s *server) getHistory(params json.RawMessage) (any, error) {
+	var p getHistoryParams
+	err := decodeParams(params, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	var modRoot string
+	if p.Dir != "" {
+		dir, err := filepath.Abs(p.Dir)
+		if err != nil {
+			return nil, err
+		}
+
+		modRoot = lookupModuleRoot(dir)
+		if modRoot == "" {
+			return nil, fmt.Errorf("no module found for %s", p.Dir)
+		}
+	}
+
+	entries, err := historyEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []HistoryEntry{}
+	for _, he := range entries {
+		if modRoot != "" && he.Dir != modRoot {
+			continue
+		}
+
+		filtered = append(filtered, he)
+		if len(filtered) == p.Limit {
+			break
+		}
+	}
+
+	return struct{ Entries []HistoryEntry }{filtered}, nil
+}
+
+// rerunLastParams are the params of rerunLast.
+type rerunLastParams struct {
+	// Dir picks the module whose last run is rerun, the working directory when empty.
+	Dir string
+}
+
+// rerunLast reruns the last run in a module like gotest -r.
+func (s *server) rerunLast(params json.RawMessage) (any, error) {
+	p := rerunLastParams{Dir: "."}
+	err := decodeParams(params, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := filepath.Abs(p.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	he, err := lastCommandIn(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// debug sessions from the terminal expect to be given its input
+	if len(he.Args) == 0 || he.Args[0] != "go" {
+		return nil, errors.New("the last run was a debug session, start one with debugTest instead")
+	}
+
+	return s.startRun(func(r *serverRun) (bool, error) {
+		s.notify("runStarted", runStarted{RunID: r.ID})
+		return runHistoryEntry(he), nil
+	})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_readMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "messages back to back",
+			stream:   "Content-Length: 2\r\n\r\n{}Content-Type: application/json\r\ncontent-length: 4\r\n\r\nnull",
+			expected: []string{"{}", "null"},
+		},
+		{name: "no length", stream: "Content-Type: application/json\r\n\r\n{}", wantErr: true},
+		{name: "cut off", stream: "Content-Length: 10\r\n\r\n{}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.stream))
+
+			var bodies []string
+			for {
+				body, err := readMessage(r)
+				if err == io.EOF {
+					break
+				}
+
+				if tt.wantErr {
+					assert.Error(t, err)
+					return
+				}
+
+				assert.NoError(t, err)
+				bodies = append(bodies, string(body))
+			}
+
+			assert.False(t, tt.wantErr, "expected an error")
+			assert.Equal(t, tt.expected, bodies)
+		})
+	}
+}
+
+// rpcClient talks to a server over pipes, keeping the notifications sent while waiting for a response.
+type rpcClient struct {
+	in            *io.PipeWriter
+	out           *bufio.Reader
+	lastID        int
+	notifications []rpcNotification
+}
+
+func (c *rpcClient) call(t *testing.T, method string, params any) (json.RawMessage, *rpcError) {
+	t.Helper()
+
+	c.lastID++
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": c.lastID, "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+
+	for {
+		body, err := readMessage(c.out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var msg struct {
+			ID     int
+			Method string
+			Params json.RawMessage
+			Result json.RawMessage
+			Error  *rpcError
+		}
+
+		err = json.Unmarshal(body, &msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.Method != "" {
+			c.notifications = append(c.notifications, rpcNotification{Method: msg.Method, Params: msg.Params})
+			continue
+		}
+
+		assert.Equal(t, c.lastID, msg.ID)
+		return msg.Result, msg.Error
+	}
+}
+
+func Test_server(t *testing.T) {
+	isolateHistory(t)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/served\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(dir, "a_test.go"), "package served\n\nimport \"testing\"\n\n"+
+		"func TestServed(t *testing.T) {\n\tt.Run(\"sub\", func(t *testing.T) {})\n}\n")
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	s := newServer(outW)
+	testEventListener = s.streamEvent
+	t.Cleanup(func() { testEventListener = nil })
+
+	done := make(chan error)
+	go func() {
+		done <- s.serve(inR)
+		outW.Close()
+	}()
+
+	c := &rpcClient{in: inW, out: bufio.NewReader(outR)}
+
+	result, rpcErr := c.call(t, "listTests", listTestsParams{Dir: dir})
+	assert.Nil(t, rpcErr)
+
+	var listed struct{ Tests []Test }
+	assert.NoError(t, json.Unmarshal(result, &listed))
+	assert.Equal(t, []string{"TestServed", "TestServed/sub"}, testNames(listed.Tests))
+
+	result, rpcErr = c.call(t, "runTests", runTestsParams{Tests: listed.Tests[1:]})
+	assert.Nil(t, rpcErr)
+
+	var run runResult
+	assert.NoError(t, json.Unmarshal(result, &run))
+	assert.Equal(t, runResult{RunID: 1, Pass: true}, run)
+
+	var methods []string
+	var passed []string
+	for _, n := range c.notifications {
+		methods = append(methods, n.Method)
+
+		var params struct {
+			RunID int
+			Event testEvent
+		}
+
+		assert.NoError(t, json.Unmarshal(n.Params.(json.RawMessage), &params))
+		assert.Equal(t, 1, params.RunID)
+		if params.Event.Action == "pass" && params.Event.Test != "" {
+			passed = append(passed, params.Event.Test)
+		}
+	}
+
+	assert.Equal(t, "runStarted", methods[0])
+	assert.Equal(t, []string{"TestServed/sub", "TestServed"}, passed)
+
+	result, rpcErr = c.call(t, "getHistory", getHistoryParams{Dir: dir, Limit: 1})
+	assert.Nil(t, rpcErr)
+
+	var history struct{ Entries []HistoryEntry }
+	assert.NoError(t, json.Unmarshal(result, &history))
+	if assert.Len(t, history.Entries, 1) {
+		assert.Equal(t, dir, history.Entries[0].Dir)
+		assert.True(t, history.Entries[0].LastRunStatus)
+	}
+
+	// relative directories are resolved against the server's working directory
+	t.Chdir(dir)
+	result, rpcErr = c.call(t, "getHistory", getHistoryParams{Dir: "."})
+	assert.Nil(t, rpcErr)
+	assert.NoError(t, json.Unmarshal(result, &history))
+	assert.Len(t, history.Entries, 1)
+
+	_, rpcErr = c.call(t, "getHistory", getHistoryParams{Dir: t.TempDir()})
+	if assert.NotNil(t, rpcErr) {
+		assert.Contains(t, rpcErr.Message, "no module found")
+	}
+
+	result, rpcErr = c.call(t, "rerunLast", rerunLastParams{Dir: dir})
+	assert.Nil(t, rpcErr)
+	assert.NoError(t, json.Unmarshal(result, &run))
+	assert.Equal(t, runResult{RunID: 2, Pass: true}, run)
+
+	result, rpcErr = c.call(t, "cancelRun", cancelRunParams{RunID: 2})
+	assert.Nil(t, rpcErr)
+	assert.JSONEq(t, `{"Cancelled":false}`, string(result))
+
+	_, rpcErr = c.call(t, "runTests", nil)
+	assert.Equal(t, &rpcError{Code: rpcInvalidParams, Message: "no tests to run"}, rpcErr)
+
+	_, rpcErr = c.call(t, "nope", nil)
+	assert.Equal(t, &rpcError{Code: rpcMethodNotFound, Message: "unknown method nope"}, rpcErr)
+
+	inW.Close()
+	assert.NoError(t, <-done)
+}
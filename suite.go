@@ -0,0 +1,79 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// testifySuitePkg is the import path of testify's suite package.
+const testifySuitePkg = "github.com/stretchr/testify/suite"
+
+// isSuiteRunCall reports if c is a call to suite.Run(t, s).
+func (tp *testPackage) isSuiteRunCall(c *ast.CallExpr) bool {
+	sel, ok := c.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Run" || len(c.Args) != 2 {
+		return false
+	}
+
+	fn, ok := tp.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != testifySuitePkg {
+		return false
+	}
+
+	return fn.Type().(*types.Signature).Recv() == nil
+}
+
+// suiteTests returns an entry for every Test method of the suite passed to suite.Run, along with any subtests the
+// methods start with s.Run. Testify runs each method as a subtest of the wrapping test.
+func (tp *testPackage) suiteTests(namer *subtestNamer, path, parentTestName string, c *ast.CallExpr) []Test {
+	typ := tp.TypesInfo.TypeOf(c.Args[1])
+	if typ == nil {
+		return nil
+	}
+
+	var tests []Test
+
+	// method sets are sorted by name which matches the order testify runs them in
+	methods := types.NewMethodSet(typ)
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.At(i).Obj()
+		if !strings.HasPrefix(method.Name(), "Test") {
+			continue
+		}
+
+		testName, _ := namer.name(parentTestName, method.Name())
+		test := Test{
+			File:          path,
+			Name:          testName,
+			FilePath:      path,
+			LineNumber:    tp.Fset.Position(c.Pos()).Line,
+			EndLineNumber: tp.Fset.Position(c.End()).Line,
+			SuiteMethod:   method.Name(),
+		}
+
+		// methods promoted from suites in other packages have no declaration to look at
+		decl, ok := tp.funcs[method]
+		if !ok || decl.Body == nil {
+			tests = append(tests, test)
+			continue
+		}
+
+		// suite methods can be declared in another file of the package
+		declPos := tp.Fset.Position(decl.Pos())
+		if declPos.Filename != tp.Fset.Position(c.Pos()).Filename {
+			test.FilePath = declPos.Filename
+		}
+
+		test.LineNumber = declPos.Line
+		test.EndLineNumber = tp.Fset.Position(decl.End()).Line
+		tests = append(tests, test)
+
+		for _, subtest := range tp.subtests(namer, test.FilePath, testName, decl.Body, nil) {
+			subtest.SuiteMethod = method.Name()
+			tests = append(tests, subtest)
+		}
+	}
+
+	return tests
+}
@@ -0,0 +1,34 @@
+package testdata
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+const prefix = "const"
+
+func Test_ComputedNames(t *testing.T) {
+	t.Run(prefix+"-name", func(t *testing.T) {})
+
+	tests := []struct {
+		in int
+	}{
+		{in: 1},
+		{in: 2},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case-%d", i), func(t *testing.T) {})
+
+		name := fmt.Sprintf("in=%d", tt.in)
+		t.Run(name, func(t *testing.T) {})
+	}
+
+	for i := range 2 {
+		t.Run("n"+strconv.Itoa(i), func(t *testing.T) {})
+	}
+
+	t.Run(fmt.Sprintf("rand-%d", rand.Int()), func(t *testing.T) {})
+}
@@ -0,0 +1,9 @@
+package testdata
+
+import "testing"
+
+func Test_DuplicateNames(t *testing.T) {
+	t.Run("with space", func(t *testing.T) {})
+	t.Run("dup", func(t *testing.T) {})
+	t.Run("dup", func(t *testing.T) {})
+}
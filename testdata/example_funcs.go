@@ -0,0 +1,26 @@
+package testdata
+
+import "fmt"
+
+type Greeter struct{}
+
+func (Greeter) Hello() {}
+
+func ExampleGreeter_Hello() {
+	fmt.Println("hello")
+	// Output:
+	// hello
+}
+
+func Example_unordered() {
+	fmt.Println("a")
+	fmt.Println("b")
+	// Unordered output:
+	// b
+	// a
+}
+
+func ExampleGreeter() {
+	// no output comment so go test never runs this
+	_ = Greeter{}
+}
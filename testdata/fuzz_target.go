@@ -0,0 +1,12 @@
+package testdata
+
+import "testing"
+
+func Fuzz_Reverse(f *testing.F) {
+	f.Add("hello")
+	f.Add("world")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		t.Log(s)
+	})
+}
@@ -0,0 +1,36 @@
+package testdata
+
+import "testing"
+
+func runCase(t *testing.T, name string, fn func(t *testing.T)) {
+	t.Run(name, fn)
+}
+
+func runNested(t *testing.T, name string) {
+	runCase(t, name, func(t *testing.T) {})
+}
+
+func Test_Helpers(t *testing.T) {
+	runCase(t, "direct", func(t *testing.T) {})
+	runNested(t, "nested")
+
+	check := func(name string) {
+		t.Run(name, func(t *testing.T) {})
+	}
+
+	check("closure")
+}
+
+func callCase(t *testing.T, fn func(t *testing.T)) {
+	fn(t)
+}
+
+func Test_HelperClosures(t *testing.T) {
+	runCase(t, "outer", func(t *testing.T) {
+		t.Run("inner", func(t *testing.T) {})
+	})
+
+	callCase(t, func(t *testing.T) {
+		t.Run("called", func(t *testing.T) {})
+	})
+}
@@ -0,0 +1,27 @@
+package testdata
+
+import "testing"
+
+const constantCase = "constant"
+
+func Test_MapTable(t *testing.T) {
+	tests := map[string]struct {
+		in string
+	}{
+		"literal":    {in: "a"},
+		constantCase: {in: "b"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Log(tc.in)
+		})
+	}
+}
+
+func Test_MapTableInline(t *testing.T) {
+	for name := range map[string]int{"one": 1, "two": 2} {
+		name := name
+		t.Run(name, func(t *testing.T) {})
+	}
+}
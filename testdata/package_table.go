@@ -0,0 +1,40 @@
+package testdata
+
+import "testing"
+
+var sharedTests = []struct {
+	name string
+}{
+	{name: "shared1"},
+	{name: "shared2"},
+}
+
+func Test_PackageTable(t *testing.T) {
+	for _, tt := range sharedTests {
+		t.Run(tt.name, func(t *testing.T) {})
+	}
+}
+
+func Test_OtherFileTable(t *testing.T) {
+	for _, tc := range otherFileTests {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {})
+	}
+}
+
+func Test_HelperTable(t *testing.T) {
+	for _, tt := range helperTests() {
+		t.Run(tt.name, func(t *testing.T) {})
+	}
+}
+
+func Test_NamedSliceTable(t *testing.T) {
+	tests := namedCases{
+		{"named1", 1},
+		{"named2", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {})
+	}
+}
@@ -0,0 +1,23 @@
+package testdata
+
+type namedCase struct {
+	name  string
+	value int
+}
+
+type namedCases []namedCase
+
+var otherFileTests = []*struct {
+	id    int
+	title string
+}{
+	{1, "other1"},
+	{id: 2, title: "other2"},
+}
+
+func helperTests() []namedCase {
+	return []namedCase{
+		{name: "helper1"},
+		{name: "helper2"},
+	}
+}
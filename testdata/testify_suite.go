@@ -0,0 +1,23 @@
+package testdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ExampleSuite struct {
+	suite.Suite
+}
+
+func (s *ExampleSuite) TestFirst() {
+	s.Run("nested", func() {})
+}
+
+func (s *ExampleSuite) TestSecond() {}
+
+func (s *ExampleSuite) helper() {}
+
+func TestExampleSuite(t *testing.T) {
+	suite.Run(t, new(ExampleSuite))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// watchTests reruns tests every time a .go file in the module of dir is saved. With -s the selected tests are rerun
+// and with -r the last run, otherwise the packages of the changed files are run.
+func watchTests(dir string) error {
+	root := lookupModuleRoot(dir)
+	if root == "" {
+		return errors.New("no module found to watch")
+	}
+
+	wait, err := time.ParseDuration(globalConfig.WatchDebounce)
+	if err != nil {
+		return fmt.Errorf("invalid WatchDebounce %q: %w", globalConfig.WatchDebounce, err)
+	}
+
+	var run func(changed []string)
+	switch {
+	case *subtest:
+		availableTests, err := getTestsFromDir(dir, kindTest)
+		if err != nil {
+			return fmt.Errorf("error getting tests: %w", err)
+		}
+
+		if len(availableTests) == 0 {
+			fmt.Println("No tests found in the directory")
+			return nil
+		}
+
+		selected := selectTests(mergeLearnedTests(availableTests), "")
+		run = func([]string) {
+			// failures are in the summary already, watching goes on either way
+			err := runTests(selected)
+			if err != nil && !errors.Is(err, errTestsFailed) {
+				fmt.Println(err)
+			}
+		}
+
+	case *rerun:
+		he, err := getLastCommand()
+		if err != nil {
+			return fmt.Errorf("error getting last command: %w", err)
+		}
+
+		run = func([]string) {
+			runHistoryEntry(he)
+		}
+
+	default:
+		run = func(changed []string) {
+			for _, pkgDir := range changedPackages(changed) {
+				cmd, outcome := executeTests(Test{File: pkgDir})
+				logRunHistory(cmd, outcome)
+			}
+		}
+	}
+
+	changes := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- watchFiles(root, changes, nil)
+	}()
+
+	// tests picked with -s or -r run straight away, otherwise there's nothing to run until something changes
+	if *subtest || *rerun {
+		run(nil)
+	}
+
+	fmt.Println("Watching", root, "for changes")
+
+	return debounce(changes, errs, wait, func(changed []string) {
+		// clear the screen so only the latest run is shown
+		fmt.Print("\033[H\033[2J")
+		run(changed)
+		fmt.Println("Watching", root, "for changes")
+	})
+}
+
+// debounce calls run with the files sent on changes once none have been sent for wait, so a burst of writes, IE a
+// formatter rewriting files after a save, only runs once. It returns once an error, or nil, is sent on errs.
+func debounce(changes <-chan string, errs <-chan error, wait time.Duration, run func(changed []string)) error {
+	pending := map[string]bool{}
+	var timer <-chan time.Time
+	for {
+		select {
+		case path := <-changes:
+			pending[path] = true
+			timer = time.After(wait)
+		case <-timer:
+			changed := slices.Sorted(maps.Keys(pending))
+			clear(pending)
+			timer = nil
+
+			run(changed)
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// changedPackages returns the package directories of the changed files that still exist.
+func changedPackages(changed []string) []string {
+	dirs := map[string]bool{}
+	for _, path := range changed {
+		dir := filepath.Dir(path)
+		if _, err := os.Stat(dir); err == nil {
+			dirs[dir] = true
+		}
+	}
+
+	return slices.Sorted(maps.Keys(dirs))
+}
@@ -0,0 +1,122 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchMask is the inotify events that mean a file was saved, added or removed. Editors that save by writing a
+// temporary file and renaming it over the original show up as IN_MOVED_TO.
+const watchMask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_CREATE | unix.IN_DELETE
+
+// watchFiles sends the path of every .go file saved, added or removed under root to changes until done is closed.
+// Directories created while watching are watched as well.
+func watchFiles(root string, changes chan<- string, done <-chan struct{}) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("error starting inotify: %w", err)
+	}
+	defer unix.Close(fd)
+
+	dirs := map[int]string{}
+	watchDir := func(dir string) error {
+		return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			// directories can be removed while they're being walked
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+
+			if path != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+
+			wd, err := unix.InotifyAddWatch(fd, path, watchMask)
+			if err != nil {
+				return fmt.Errorf("error watching %s: %w", path, err)
+			}
+
+			dirs[wd] = path
+
+			return nil
+		})
+	}
+
+	err = watchDir(root)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+
+		// poll with a timeout so closing done is noticed
+		n, err := unix.Poll([]unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}, 250)
+		switch {
+		case errors.Is(err, unix.EINTR) || n == 0:
+			continue
+		case err != nil:
+			return fmt.Errorf("error waiting for inotify events: %w", err)
+		}
+
+		n, err = unix.Read(fd, buf)
+		switch {
+		case errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR):
+			continue
+		case err != nil:
+			return fmt.Errorf("error reading inotify events: %w", err)
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(ev.Len)]), "\x00")
+			offset = nameStart + int(ev.Len)
+
+			dir, ok := dirs[int(ev.Wd)]
+			if !ok {
+				continue
+			}
+
+			// the watch is removed along with its directory
+			if ev.Mask&unix.IN_IGNORED != 0 {
+				delete(dirs, int(ev.Wd))
+				continue
+			}
+
+			path := filepath.Join(dir, name)
+			if ev.Mask&unix.IN_ISDIR != 0 {
+				if ev.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 && !skipDir(name) {
+					err = watchDir(path)
+					if err != nil {
+						return err
+					}
+				}
+
+				continue
+			}
+
+			if !strings.HasSuffix(name, ".go") {
+				continue
+			}
+
+			select {
+			case changes <- path:
+			case <-done:
+				return nil
+			}
+		}
+	}
+}
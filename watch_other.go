@@ -0,0 +1,61 @@
+//go:build !linux
+
+package main
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// watchFiles sends the path of every .go file saved, added or removed under root to changes until done is closed.
+// Without inotify the files are polled for changes using the same stamps as the discovery cache.
+func watchFiles(root string, changes chan<- string, done <-chan struct{}) error {
+	last, err := scanPackageDirs(root)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := scanPackageDirs(root)
+		if err != nil {
+			return err
+		}
+
+		var changed []string
+		for dir, pkgDir := range current {
+			for name, stamp := range pkgDir.Files {
+				old, ok := last[dir].Files[name]
+				if !ok || old.Size != stamp.Size || !old.ModTime.Equal(stamp.ModTime) {
+					changed = append(changed, filepath.Join(dir, name))
+				}
+			}
+		}
+
+		for dir, pkgDir := range last {
+			for name := range pkgDir.Files {
+				if _, ok := current[dir].Files[name]; !ok {
+					changed = append(changed, filepath.Join(dir, name))
+				}
+			}
+		}
+
+		last = current
+
+		for _, path := range changed {
+			select {
+			case changes <- path:
+			case <-done:
+				return nil
+			}
+		}
+	}
+}
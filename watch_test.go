@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_debounce(t *testing.T) {
+	changes := make(chan string)
+	stop := make(chan error)
+	result := make(chan error)
+
+	runs := make(chan []string, 2)
+	go func() {
+		result <- debounce(changes, stop, 50*time.Millisecond, func(changed []string) {
+			runs <- changed
+		})
+	}()
+
+	// a burst of writes only runs once
+	changes <- "b.go"
+	changes <- "a.go"
+	changes <- "b.go"
+
+	select {
+	case changed := <-runs:
+		assert.Equal(t, []string{"a.go", "b.go"}, changed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a run")
+	}
+
+	changes <- "c.go"
+	assert.Equal(t, []string{"c.go"}, <-runs)
+
+	stop <- nil
+	assert.NoError(t, <-result)
+}
+
+func Test_watchFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/watch\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package watch\n")
+
+	changes := make(chan string)
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- watchFiles(dir, changes, done)
+	}()
+	defer func() {
+		close(done)
+		assert.NoError(t, <-errs)
+	}()
+
+	// give the watcher time to start, then save a file in a new package
+	time.Sleep(600 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "pkg", "b.go"), "package pkg\n")
+	time.Sleep(600 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "pkg", "b.go"), "package pkg\n\nvar B = 1\n")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not go")
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case path := <-changes:
+			assert.Equal(t, ".go", filepath.Ext(path))
+			if path == filepath.Join(dir, "pkg", "b.go") {
+				return
+			}
+		case <-deadline:
+			t.Fatal("expected a change to pkg/b.go")
+		}
+	}
+}